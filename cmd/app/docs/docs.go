@@ -14,7 +14,7 @@ const docTemplate = `{
         "/api/users": {
             "get": {
                 "summary": "Получить всех пользователей",
-                "description": "Возвращает список всех пользователей с возможностью фильтрации, пагинации и сортировки",
+                "description": "Возвращает страницу пользователей с фильтрацией, сортировкой и курсорной пагинацией. Фильтр записывается как field=value или field[op]=value, где op — eq, ne, contains, prefix, in (значения через запятую), gt, gte, lt, lte. Несовместимые изменения: ответ — объект {data, total, next} вместо массива, а параметр offset больше не поддерживается — следующую страницу запрашивают с cursor из поля next.",
                 "tags": [
                     "users"
                 ],
@@ -24,18 +24,45 @@ const docTemplate = `{
                         "in": "query",
                         "schema": {
                             "type": "integer",
-                            "default": 1
+                            "default": 20,
+                            "maximum": 100
                         },
-                        "description": "Количество записей для возврата"
+                        "description": "Количество записей на странице"
+                    },
+                    {
+                        "name": "cursor",
+                        "in": "query",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "description": "Курсор следующей страницы из поля next предыдущего ответа"
                     },
                     {
                         "name": "offset",
                         "in": "query",
+                        "deprecated": true,
                         "schema": {
-                            "type": "integer",
-                            "default": 0
+                            "type": "integer"
                         },
-                        "description": "Смещение записей для возврата"
+                        "description": "Не поддерживается: запрос с offset отклоняется с кодом 400, используйте cursor"
+                    },
+                    {
+                        "name": "sort",
+                        "in": "query",
+                        "schema": {
+                            "type": "string",
+                            "example": "surname,-createdAt"
+                        },
+                        "description": "Поля сортировки через запятую, минус — по убыванию"
+                    },
+                    {
+                        "name": "includeDeleted",
+                        "in": "query",
+                        "schema": {
+                            "type": "boolean",
+                            "default": false
+                        },
+                        "description": "Включить удалённых пользователей (только для администратора)"
                     },
                     {
                         "name": "passport_number",
@@ -84,9 +111,22 @@ const docTemplate = `{
                         "content": {
                             "application/json": {
                                 "schema": {
-                                    "type": "array",
-                                    "items": {
-                                        "$ref": "#/components/schemas/User"
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/components/schemas/User"
+                                            }
+                                        },
+                                        "total": {
+                                            "type": "integer",
+                                            "description": "Число пользователей, подходящих под фильтры"
+                                        },
+                                        "next": {
+                                            "type": "string",
+                                            "description": "Курсор следующей страницы; отсутствует на последней"
+                                        }
                                     }
                                 }
                             }
@@ -101,7 +141,7 @@ const docTemplate = `{
                                     "properties": {
                                         "error": {
                                             "type": "string",
-                                            "example": "Invalid limit value"
+                                            "example": "The \"offset\" parameter is not supported, use \"cursor\""
                                         }
                                     }
                                 }
@@ -1,42 +1,322 @@
 package main
 
 import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
 	_ "time-tracker/cmd/app/docs"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/config"
 	"time-tracker/internal/controllers"
 	db "time-tracker/internal/database"
+	"time-tracker/internal/etag"
+	"time-tracker/internal/graph"
+	"time-tracker/internal/grpcapi"
+	"time-tracker/internal/health"
+	"time-tracker/internal/idempotency"
+	"time-tracker/internal/jobs"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/metrics"
+	"time-tracker/internal/migrate"
+	"time-tracker/internal/outbox"
+	"time-tracker/internal/peopleinfo"
+	"time-tracker/internal/problem"
+	"time-tracker/internal/ratelimit"
+	"time-tracker/internal/requestid"
+	"time-tracker/internal/server"
+	"time-tracker/internal/stream"
+	"time-tracker/internal/tracing"
+	"time-tracker/migrations"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 func main() {
-	dbpool := db.ConnectDatabase()
+	// A server that fails makes the process exit with status 1 once the
+	// deferred cleanup has run, so that supervisors restart it.
+	var failed atomic.Bool
+	defer func() {
+		if failed.Load() {
+			os.Exit(1)
+		}
+	}()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := logger.Configure(cfg.Log); err != nil {
+		log.Fatalf("invalid log configuration: %v\n", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	dbpool, err := db.ConnectDatabase(cfg.Database)
+	if err != nil {
+		log.Fatalln(err)
+	}
 	defer dbpool.Close()
 
 	db.Pool = dbpool
 
+	migrator, err := migrate.New(dbpool, migrations.FS)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), migrator, os.Args[2:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("failed to apply migrations: %v\n", err)
+		}
+	}
+
+	tokens, err := auth.ParseTokens(cfg.Auth.Tokens)
+	if err != nil {
+		log.Fatalf("invalid API_TOKENS: %v\n", err)
+	}
+
 	userRepo := db.NewUserRepository(dbpool)
 	taskRepo := db.NewTaskRepository(dbpool)
+	auditRepo := db.NewAuditRepository(dbpool)
+	searchRepo := db.NewSearchRepository(dbpool)
+	idempotencyRepo := db.NewIdempotencyRepository(dbpool)
+	rateLimitRepo := db.NewRateLimitRepository(dbpool)
+	webhookRepo := db.NewWebhookRepository(dbpool)
+	outboxRepo := db.NewOutboxRepository(dbpool)
 
-	userController := controllers.NewUserController(userRepo)
+	peopleInfo := peopleinfo.NewClient(cfg.PeopleInfo)
+
+	userController := controllers.NewUserController(userRepo, peopleInfo)
 	taskController := controllers.NewTaskController(taskRepo)
+	auditController := controllers.NewAuditController(auditRepo)
+	searchController := controllers.NewSearchController(searchRepo)
+	webhookController := controllers.NewWebhookController(webhookRepo)
+
+	metrics.RegisterPool(dbpool)
+	metrics.RegisterRunningTasks(taskRepo.CountRunningTasks)
+
+	purger := jobs.NewPurger(userRepo, idempotencyRepo, rateLimitRepo, cfg.Retention.SoftDelete, cfg.Retention.PurgeInterval)
+	dispatcher := jobs.NewDispatcher(webhookRepo, cfg.Webhooks)
+
+	var sinks []outbox.Sink
+	for _, name := range cfg.Outbox.SinkList() {
+		switch name {
+		case "webhooks":
+			sinks = append(sinks, outbox.NewWebhookSink(webhookRepo))
+		case "stdout":
+			sinks = append(sinks, outbox.NewStdoutSink(os.Stdout))
+		case "nats":
+			natsSink, err := outbox.NewNATSSink(cfg.Outbox.NATSURL, cfg.Outbox.NATSSubjectPrefix, cfg.Outbox.NATSTimeout)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			defer natsSink.Close()
+			sinks = append(sinks, natsSink)
+		}
+	}
+	relay := jobs.NewRelay(outboxRepo, sinks, cfg.Outbox)
+	hub := stream.NewHub(dbpool, taskRepo, cfg.Stream)
+	streamController := controllers.NewStreamController(hub, cfg.Stream.MaxUsers)
+
+	checker := health.NewChecker(cfg.Health.CacheTTL, cfg.Health.CheckTimeout)
+	checker.Add("database", true, dbpool.Ping)
+	checker.Add("migrations", true, migrator.Check)
+	if peopleInfo.Enabled() {
+		checker.Add("peopleInfo", false, peopleInfo.Ping)
+	}
+	healthController := controllers.NewHealthController(checker, dbpool, purger, dispatcher, relay, hub)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	router := gin.Default()
+	var workers sync.WaitGroup
+	workers.Add(4)
+	go func() {
+		defer workers.Done()
+		purger.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		relay.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		hub.Run(ctx)
+	}()
 
-	api := router.Group("/api")
+	requireIfMatch := etag.Require(cfg.Auth.StrictPreconditions)
+	idempotent := idempotency.Middleware(idempotencyRepo, cfg.Idempotency)
+
+	var rateLimitStore ratelimit.Store
+	switch strings.ToLower(cfg.RateLimit.Backend) {
+	case "memory":
+		rateLimitStore = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimitStore = rateLimitRepo
+	}
+	limiter := ratelimit.New(rateLimitStore, map[string]ratelimit.Limit{
+		"read":   {Rate: cfg.RateLimit.ReadRate, Burst: cfg.RateLimit.ReadBurst},
+		"write":  {Rate: cfg.RateLimit.WriteRate, Burst: cfg.RateLimit.WriteBurst},
+		"search": {Rate: cfg.RateLimit.SearchRate, Burst: cfg.RateLimit.SearchBurst},
+	})
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(splitList(cfg.HTTP.TrustedProxies)); err != nil {
+		log.Fatalf("invalid HTTP_TRUSTED_PROXIES: %v\n", err)
+	}
+	// Handlers pass *gin.Context as context.Context to the repositories, which
+	// read the request ID and principal from the request context.
+	router.ContextWithFallback = true
+	// problem.Middleware must stay last so that the outer middlewares see the
+	// status of the rendered error.
+	router.Use(
+		requestid.Middleware(),
+		logger.Middleware(),
+		metrics.Middleware(),
+		tracing.Middleware(cfg.Tracing.ServiceName),
+		problem.Recovery(),
+		problem.Middleware(),
+	)
+	router.NoRoute(problem.NoRoute)
+
+	router.GET("/healthz", healthController.Liveness)
+	router.GET("/readyz", healthController.Readiness)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/debug/status", auth.Middleware(tokens), auth.RequireAdmin(), healthController.Status)
+
+	// Health, metrics and debug endpoints are not rate limited.
+	api := router.Group("/api", auth.Middleware(tokens))
+	read := api.Group("", limiter.Middleware("read"))
 	{
-		api.GET("/users", userController.GetUsers)
-		api.POST("/users", userController.AddUser)
-		api.PUT("/users/:userID", userController.UpdateUser)
-		api.DELETE("/users/:userID", userController.DeleteUser)
+		read.GET("/users", userController.GetUsers)
+		read.GET("/users/:userID", userController.GetUser)
+		read.GET("/users/:userID/export", auth.RequireAdmin(), userController.ExportUser)
+		read.GET("/users/:userID/tasks", taskController.GetUserTasksByPeriod)
+		read.GET("/tasks/:taskID", taskController.GetTask)
+		read.GET("/stream", streamController.Events)
+		read.GET("/stream/ws", streamController.WebSocket)
+		read.GET("/audit", auth.RequireAdmin(), auditController.GetEvents)
+		read.GET("/webhooks", auth.RequireAdmin(), webhookController.GetSubscriptions)
+		read.GET("/webhooks/:webhookID/deliveries", auth.RequireAdmin(), webhookController.GetDeliveries)
+	}
+	write := api.Group("", limiter.Middleware("write"))
+	{
+		write.POST("/users", idempotent, userController.AddUser)
+		write.PUT("/users/:userID", requireIfMatch, userController.UpdateUser)
+		write.PATCH("/users/:userID", requireIfMatch, userController.PatchUser)
+		write.DELETE("/users/:userID", requireIfMatch, userController.DeleteUser)
+		write.POST("/users/:userID/restore", auth.RequireAdmin(), userController.RestoreUser)
+		write.POST("/users/:userID/erase", auth.RequireAdmin(), userController.EraseUser)
+		write.POST("/tasks/start", idempotent, taskController.StartTask)
+		write.POST("/tasks/end/:taskID", idempotent, taskController.EndTask)
+		write.POST("/webhooks", auth.RequireAdmin(), webhookController.CreateSubscription)
+		write.DELETE("/webhooks/:webhookID", auth.RequireAdmin(), webhookController.DeleteSubscription)
+		write.POST("/webhooks/deliveries/:deliveryID/redeliver", auth.RequireAdmin(), webhookController.Redeliver)
+	}
+	api.GET("/search", limiter.Middleware("search"), searchController.Search)
 
-		api.GET("/users/:userID/tasks", taskController.GetUserTasksByPeriod)
-		api.POST("/tasks/start", taskController.StartTask)
-		api.POST("/tasks/end/:taskID", taskController.EndTask)
+	// A single GraphQL query can aggregate over many users, so it shares the
+	// limit of search.
+	graphqlHandler := gin.WrapH(graph.NewHandler(userRepo, taskRepo, cfg.GraphQL))
+	router.POST("/graphql", auth.Middleware(tokens), limiter.Middleware("search"), graphqlHandler)
+	if cfg.GraphQL.Dev {
+		router.GET("/graphql", gin.WrapH(graph.Playground("/graphql")))
 	}
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	router.Run(":8080")
 
+	srv, err := server.New(cfg.HTTP, router)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if cfg.GRPC.Addr != "" {
+		grpcServer := grpcapi.New(cfg.GRPC, srv.TLSConfig(), tokens, checker,
+			grpcapi.NewUserService(userRepo, peopleInfo, cfg.Auth.StrictPreconditions),
+			grpcapi.NewTaskService(taskRepo, hub, cfg.Stream.MaxUsers),
+		)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := grpcServer.Run(ctx); err != nil {
+				logger.Logger.Error("gRPC server failed",
+					"error", err,
+				)
+				failed.Store(true)
+				stop()
+			}
+		}()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := srv.ReloadCertificate(); err != nil {
+				logger.Logger.Error("Failed to reload TLS certificate",
+					"error", err,
+				)
+			}
+		}
+	}()
+
+	if err := srv.Run(ctx); err != nil {
+		logger.Logger.Error("HTTP server failed",
+			"error", err,
+		)
+		failed.Store(true)
+	}
+	stop()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(cfg.HTTP.ShutdownTimeout):
+		logger.Logger.Warn("Timed out waiting for background workers")
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Logger.Error("Failed to flush traces",
+			"error", err,
+		)
+	}
+	logger.Logger.Info("Shutdown complete")
+}
+
+// splitList splits a comma separated setting, dropping empty entries.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"time-tracker/internal/migrate"
+)
+
+const migrateUsage = `usage: app migrate <command> [--allow-destructive]
+
+commands:
+  up          apply all pending migrations
+  down [N]    roll back the last N migrations (default 1)
+  to N        migrate up or down to version N (0 rolls back everything)
+  status      list migrations and whether they are applied`
+
+// runMigrate implements the "app migrate" subcommands.
+func runMigrate(ctx context.Context, m *migrate.Migrator, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	allowDestructive := flags.Bool("allow-destructive", false, "allow down migrations that drop tables, columns or rows")
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, migrateUsage) }
+
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing migrate command")
+	}
+	command := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	rest := flags.Args()
+
+	switch command {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(rest) > 0 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", rest[0])
+			}
+			steps = n
+		}
+		return m.Down(ctx, steps, *allowDestructive)
+	case "to":
+		if len(rest) != 1 {
+			return fmt.Errorf("migrate to requires a version")
+		}
+		version, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", rest[0])
+		}
+		return m.To(ctx, version, *allowDestructive)
+	case "status":
+		states, err := m.Status(ctx)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+		for _, state := range states {
+			applied, note := "pending", ""
+			if state.Applied != nil {
+				applied = state.Applied.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if state.Modified {
+				note = "checksum mismatch"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", state.Version, state.Name, applied, note)
+		}
+		w.Flush()
+		return err
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"time-tracker/internal/models"
+)
+
+const (
+	maxAttempts = 3
+	retryDelay  = 500 * time.Millisecond
+)
+
+// Client calls the REST API of the time tracker.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func NewClient(cfg *Config) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(cfg.Server, "/"),
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// APIError is a problem returned by the API.
+type APIError struct {
+	Type   string `json:"type"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Errors []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	for _, fe := range e.Errors {
+		msg += fmt.Sprintf("\n  %s: %s", fe.Field, fe.Message)
+	}
+	return msg
+}
+
+// Slug is the last part of the problem type, e.g. "task-already-ended".
+func (e *APIError) Slug() string {
+	return strings.TrimPrefix(e.Type, "/problems/")
+}
+
+// StartTask starts a task. Retries are safe: the request carries an
+// idempotency key.
+func (c *Client) StartTask(ctx context.Context, userID int, description string) (*models.Task, error) {
+	body := models.Request{UserID: uint(userID), Description: description}
+	var task models.Task
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/start", nil, body, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// EndTask ends a task. A version other than zero is sent as If-Match.
+func (c *Client) EndTask(ctx context.Context, taskID, version int) (*models.Task, error) {
+	header := http.Header{}
+	if version > 0 {
+		header.Set("If-Match", strconv.Quote(strconv.Itoa(version)))
+	}
+	var task models.Task
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/end/"+strconv.Itoa(taskID), header, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) GetTask(ctx context.Context, taskID int) (*models.Task, error) {
+	var task models.Task
+	if err := c.do(ctx, http.MethodGet, "/api/tasks/"+strconv.Itoa(taskID), nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetUserTasks returns the tasks of a user that started and ended within
+// the period, longest first.
+func (c *Client) GetUserTasks(ctx context.Context, userID int, start, end time.Time) ([]models.Task, error) {
+	query := url.Values{}
+	query.Set("start", start.Format(time.RFC3339))
+	query.Set("end", end.Format(time.RFC3339))
+	path := fmt.Sprintf("/api/users/%d/tasks?%s", userID, query.Encode())
+
+	tasks := []models.Task{}
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &tasks); err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		// The API returns null rather than an empty list.
+		tasks = []models.Task{}
+	}
+	return tasks, nil
+}
+
+// do sends a request, retrying it when the server could not be reached or
+// was unavailable. POST requests keep their idempotency key across retries,
+// so a task is never started twice.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if method == http.MethodPost {
+		header.Set("Idempotency-Key", newIdempotencyKey())
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * retryDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		var retry bool
+		retry, err = c.send(ctx, method, path, header, data, out)
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, data []byte, out interface{}) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{Status: resp.StatusCode}
+		// A body that is not a problem, e.g. from a proxy, leaves the status.
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(apiErr)
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, apiErr
+		}
+		return false, apiErr
+	}
+	if out == nil {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("invalid response from %s %s: %w", method, path, err)
+	}
+	return false, nil
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
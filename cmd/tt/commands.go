@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"time-tracker/internal/models"
+)
+
+// newFlags returns a flag set whose usage prints the synopsis of a command.
+func newFlags(name, synopsis string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: tt %s\n\nflags:\n", synopsis)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func runConfig(cfg *Config, args []string) error {
+	flags := newFlags("config", "config [--server URL] [--token TOKEN] [--user ID]")
+	server := flags.String("server", "", "base URL of the API, e.g. https://tracker.example.com")
+	token := flags.String("token", "", "API token")
+	user := flags.Int("user", 0, "ID of the user tasks are tracked for")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	changed := false
+	flags.Visit(func(f *flag.Flag) { changed = true })
+	if !changed {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "file\t%s\n", cfg.path)
+		fmt.Fprintf(w, "server\t%s\n", cfg.Server)
+		fmt.Fprintf(w, "token\t%s\n", maskToken(cfg.Token))
+		fmt.Fprintf(w, "user\t%s\n", optionalID(cfg.UserID))
+		return w.Flush()
+	}
+
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+	if *user < 0 {
+		return fmt.Errorf("invalid user ID %d", *user)
+	}
+	if *user > 0 {
+		cfg.UserID = *user
+	}
+	return cfg.save()
+}
+
+func runStart(ctx context.Context, cfg *Config, args []string) error {
+	flags := newFlags("start", "start [--user ID] [--json] DESCRIPTION")
+	user := flags.Int("user", 0, "ID of the user, instead of the configured one")
+	asJSON := flags.Bool("json", false, "print the task as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	description := strings.TrimSpace(strings.Join(flags.Args(), " "))
+	if description == "" {
+		flags.Usage()
+		return errors.New("missing task description")
+	}
+	if cur := cfg.Current; cur != nil {
+		return fmt.Errorf("task #%d %q is running since %s, run tt stop first",
+			cur.TaskID, cur.Description, cur.StartTime.Local().Format("15:04"))
+	}
+	userID, err := cfg.userID(*user)
+	if err != nil {
+		return err
+	}
+
+	task, err := NewClient(cfg).StartTask(ctx, userID, description)
+	if err != nil {
+		return err
+	}
+	cfg.Current = &Current{TaskID: task.ID, Description: task.Description, StartTime: task.StartTime, Version: task.Version}
+	if err := cfg.save(); err != nil {
+		return fmt.Errorf("task #%d started but could not be remembered: %w", task.ID, err)
+	}
+
+	if *asJSON {
+		return printJSON(task)
+	}
+	fmt.Printf("Started #%d %q at %s\n", task.ID, task.Description, task.StartTime.Local().Format("15:04"))
+	return nil
+}
+
+func runStop(ctx context.Context, cfg *Config, args []string) error {
+	flags := newFlags("stop", "stop [--json] [TASK_ID]")
+	asJSON := flags.Bool("json", false, "print the task as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var taskID, version int
+	switch {
+	case flags.NArg() > 1:
+		flags.Usage()
+		return errors.New("too many arguments")
+	case flags.NArg() == 1:
+		id, err := strconv.Atoi(flags.Arg(0))
+		if err != nil || id < 1 {
+			return fmt.Errorf("invalid task ID %q", flags.Arg(0))
+		}
+		taskID = id
+		if cfg.Current != nil && cfg.Current.TaskID == id {
+			version = cfg.Current.Version
+		}
+	case cfg.Current != nil:
+		taskID, version = cfg.Current.TaskID, cfg.Current.Version
+	default:
+		return errors.New("no task is running, pass the ID of the task to stop")
+	}
+
+	task, err := NewClient(cfg).EndTask(ctx, taskID, version)
+	if err != nil {
+		// A task that is gone or was stopped elsewhere is no longer running.
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Slug() == "task-already-ended") {
+			if forgetErr := cfg.forget(taskID); forgetErr != nil {
+				return forgetErr
+			}
+		}
+		return err
+	}
+	if err := cfg.forget(taskID); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(task)
+	}
+	fmt.Printf("Stopped #%d %q after %s\n", task.ID, task.Description, formatDuration(duration(task, time.Now())))
+	return nil
+}
+
+// status is the JSON output of tt status.
+type status struct {
+	Running        bool         `json:"running"`
+	Task           *models.Task `json:"task,omitempty"`
+	ElapsedSeconds int64        `json:"elapsedSeconds,omitempty"`
+}
+
+func runStatus(ctx context.Context, cfg *Config, args []string) error {
+	flags := newFlags("status", "status [--json]")
+	asJSON := flags.Bool("json", false, "print the status as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var st status
+	if cfg.Current != nil {
+		// The task may have been stopped from another machine or the API.
+		task, err := NewClient(cfg).GetTask(ctx, cfg.Current.TaskID)
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound:
+		case err != nil:
+			return err
+		case task.EndTime == nil:
+			st = status{Running: true, Task: task, ElapsedSeconds: int64(duration(task, time.Now()).Seconds())}
+		}
+		if !st.Running {
+			if err := cfg.forget(cfg.Current.TaskID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *asJSON {
+		return printJSON(st)
+	}
+	if !st.Running {
+		fmt.Println("No task is running")
+		return nil
+	}
+	fmt.Printf("#%d %q running for %s since %s\n", st.Task.ID, st.Task.Description,
+		formatDuration(time.Duration(st.ElapsedSeconds)*time.Second), st.Task.StartTime.Local().Format("Mon 15:04"))
+	return nil
+}
+
+// report is the JSON output of tt report.
+type report struct {
+	Start         time.Time                 `json:"start"`
+	End           time.Time                 `json:"end"`
+	Tasks         []models.Task             `json:"tasks"`
+	TaskCount     int                       `json:"taskCount"`
+	TotalSeconds  int64                     `json:"totalSeconds"`
+	ByDescription []models.DescriptionTotal `json:"byDescription"`
+}
+
+func runReport(ctx context.Context, cfg *Config, args []string) error {
+	flags := newFlags("report", "report [--today | --week | --month | --from DATE [--to DATE]] [--user ID] [--json]")
+	today := flags.Bool("today", false, "report on today")
+	week := flags.Bool("week", false, "report on the current week, the default")
+	month := flags.Bool("month", false, "report on the current month")
+	from := flags.String("from", "", "first day of the period, as YYYY-MM-DD")
+	to := flags.String("to", "", "last day of the period, as YYYY-MM-DD, today by default")
+	user := flags.Int("user", 0, "ID of the user, instead of the configured one")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	start, end, err := reportPeriod(time.Now(), *today, *week, *month, *from, *to)
+	if err != nil {
+		return err
+	}
+	userID, err := cfg.userID(*user)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := NewClient(cfg).GetUserTasks(ctx, userID, start, end)
+	if err != nil {
+		return err
+	}
+	r := summarize(start, end, tasks)
+
+	if *asJSON {
+		return printJSON(r)
+	}
+	printReport(os.Stdout, r)
+	return nil
+}
+
+// reportPeriod turns the period flags of tt report into bounds in local
+// time. The end is exclusive.
+func reportPeriod(now time.Time, today, week, month bool, from, to string) (start, end time.Time, err error) {
+	set := 0
+	for _, b := range []bool{today, week, month, from != ""} {
+		if b {
+			set++
+		}
+	}
+	if set > 1 {
+		return start, end, errors.New("--today, --week, --month and --from are mutually exclusive")
+	}
+	if to != "" && from == "" {
+		return start, end, errors.New("--to requires --from")
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end = now
+	switch {
+	case today:
+		start = midnight
+	case month:
+		start = midnight.AddDate(0, 0, 1-now.Day())
+	case from != "":
+		if start, err = time.ParseInLocation(time.DateOnly, from, now.Location()); err != nil {
+			return start, end, fmt.Errorf("invalid --from date %q", from)
+		}
+		if to != "" {
+			last, err := time.ParseInLocation(time.DateOnly, to, now.Location())
+			if err != nil {
+				return start, end, fmt.Errorf("invalid --to date %q", to)
+			}
+			end = last.AddDate(0, 0, 1)
+		}
+		if !end.After(start) {
+			return start, end, errors.New("--to must not be before --from")
+		}
+	default:
+		// Weeks start on Monday.
+		start = midnight.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	}
+	return start, end, nil
+}
+
+// summarize orders the tasks by start time and totals them per description,
+// largest first.
+func summarize(start, end time.Time, tasks []models.Task) report {
+	r := report{Start: start, End: end, Tasks: tasks, TaskCount: len(tasks), ByDescription: []models.DescriptionTotal{}}
+	sortByStart(r.Tasks)
+
+	index := make(map[string]int)
+	for i := range tasks {
+		seconds := int64(duration(&tasks[i], end).Seconds())
+		r.TotalSeconds += seconds
+
+		j, ok := index[tasks[i].Description]
+		if !ok {
+			j = len(r.ByDescription)
+			index[tasks[i].Description] = j
+			r.ByDescription = append(r.ByDescription, models.DescriptionTotal{Description: tasks[i].Description})
+		}
+		r.ByDescription[j].TaskCount++
+		r.ByDescription[j].TotalSeconds += seconds
+	}
+	sortTotals(r.ByDescription)
+	return r
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config is what tt keeps between runs. It holds an API token, so it is
+// written readable by its owner only.
+type Config struct {
+	Server string `json:"server"`
+	Token  string `json:"token,omitempty"`
+	UserID int    `json:"userId,omitempty"`
+	// Current is the task started by tt that has not been stopped yet.
+	Current *Current `json:"current,omitempty"`
+
+	path string
+}
+
+type Current struct {
+	TaskID      int       `json:"taskId"`
+	Description string    `json:"description"`
+	StartTime   time.Time `json:"startTime"`
+	Version     int       `json:"version"`
+}
+
+// configPath is $TT_CONFIG, or tt/config.json in the user configuration
+// directory.
+func configPath() (string, error) {
+	if path := os.Getenv("TT_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tt", "config.json"), nil
+}
+
+// loadConfig reads the configuration file, which may not exist yet.
+// TT_SERVER and TT_TOKEN take precedence over the file, so that scripts
+// don't need one.
+func loadConfig() (*Config, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{Server: "http://localhost:8080", path: path}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	if server := os.Getenv("TT_SERVER"); server != "" {
+		cfg.Server = server
+	}
+	if token := os.Getenv("TT_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+	return cfg, nil
+}
+
+func (c *Config) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that an interrupted run doesn't
+	// leave a truncated config behind.
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// userID returns the user tasks are tracked for: the --user flag when set,
+// the configured user otherwise.
+func (c *Config) userID(flagValue int) (int, error) {
+	if flagValue > 0 {
+		return flagValue, nil
+	}
+	if c.UserID > 0 {
+		return c.UserID, nil
+	}
+	return 0, errors.New("no user configured, run tt config --user ID or pass --user")
+}
+
+// forget clears the running task if it is the given one.
+func (c *Config) forget(taskID int) error {
+	if c.Current == nil || c.Current.TaskID != taskID {
+		return nil
+	}
+	c.Current = nil
+	return c.save()
+}
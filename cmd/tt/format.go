@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"time-tracker/internal/models"
+)
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printReport(out io.Writer, r report) {
+	fmt.Fprintf(out, "%s – %s\n\n", r.Start.Format("Mon 2006-01-02 15:04"), r.End.Format("Mon 2006-01-02 15:04"))
+	if len(r.Tasks) == 0 {
+		fmt.Fprintln(out, "No completed tasks in this period")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tSTART\tEND\tDURATION\tDESCRIPTION")
+	for i := range r.Tasks {
+		task := &r.Tasks[i]
+		end := "-"
+		if task.EndTime != nil {
+			end = task.EndTime.Local().Format("15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", task.ID,
+			task.StartTime.Local().Format("Mon 01-02"), task.StartTime.Local().Format("15:04"), end,
+			formatDuration(duration(task, r.End)), task.Description)
+	}
+	w.Flush()
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DESCRIPTION\tTASKS\tTOTAL")
+	for _, total := range r.ByDescription {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", total.Description, total.TaskCount, formatDuration(time.Duration(total.TotalSeconds)*time.Second))
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%s\n", r.TaskCount, formatDuration(time.Duration(r.TotalSeconds)*time.Second))
+	w.Flush()
+}
+
+// duration is how long a task ran, up to now when it has not ended.
+func duration(task *models.Task, now time.Time) time.Duration {
+	if task.EndTime != nil {
+		return task.EndTime.Sub(task.StartTime)
+	}
+	return now.Sub(task.StartTime)
+}
+
+// formatDuration renders a duration as h:mm:ss.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < 0 {
+		d = 0
+	}
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+}
+
+func maskToken(token string) string {
+	switch {
+	case token == "":
+		return "(none)"
+	case len(token) <= 8:
+		return "********"
+	}
+	return token[:4] + "…" + token[len(token)-4:]
+}
+
+func optionalID(id int) string {
+	if id == 0 {
+		return "(none)"
+	}
+	return strconv.Itoa(id)
+}
+
+func sortByStart(tasks []models.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].StartTime.Before(tasks[j].StartTime)
+	})
+}
+
+func sortTotals(totals []models.DescriptionTotal) {
+	sort.SliceStable(totals, func(i, j int) bool {
+		return totals[i].TotalSeconds > totals[j].TotalSeconds
+	})
+}
@@ -0,0 +1,66 @@
+// Command tt starts and stops timers and reports tracked time from the
+// terminal, through the REST API of the time tracker.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const usage = `usage: tt <command> [flags] [args]
+
+commands:
+  config   show or change the server, token and user
+  start    start a task: tt start "fix login bug"
+  stop     stop the running task, or the task with the given ID
+  status   show the running task
+  report   list tasks and totals of a period: tt report --week
+
+Every command but config accepts --json for output meant for scripts.
+The config file is $TT_CONFIG or tt/config.json in the user configuration
+directory; TT_SERVER and TT_TOKEN override the values in it.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "tt:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "config":
+		return runConfig(cfg, args)
+	case "start":
+		return runStart(ctx, cfg, args)
+	case "stop":
+		return runStop(ctx, cfg, args)
+	case "status":
+		return runStatus(ctx, cfg, args)
+	case "report":
+		return runReport(ctx, cfg, args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
@@ -55,3 +55,88 @@ type TaskAlreadyEndedError struct {
 func (e *TaskAlreadyEndedError) Error() string {
 	return e.Message
 }
+
+type PreconditionFailedError struct {
+	Message string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Message
+}
+
+type ValidationError struct {
+	Message string
+	Fields  map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+type NotFoundError struct {
+	Message string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Message
+}
+
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+type PreconditionRequiredError struct {
+	Message string
+}
+
+func (e *PreconditionRequiredError) Error() string {
+	return e.Message
+}
+
+type UnsupportedMediaTypeError struct {
+	Message string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return e.Message
+}
+
+// IdempotencyKeyReusedError is returned when an Idempotency-Key is sent again
+// with a different request.
+type IdempotencyKeyReusedError struct {
+	Message string
+}
+
+func (e *IdempotencyKeyReusedError) Error() string {
+	return e.Message
+}
+
+// RequestInProgressError is returned while the first request carrying an
+// Idempotency-Key has not completed yet.
+type RequestInProgressError struct {
+	Message string
+}
+
+func (e *RequestInProgressError) Error() string {
+	return e.Message
+}
+
+type TooManyRequestsError struct {
+	Message string
+}
+
+func (e *TooManyRequestsError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"time-tracker/internal/apperrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Principal is the caller identified by an API token.
+type Principal struct {
+	Subject string
+	Admin   bool
+}
+
+type principalKey struct{}
+
+// ParseTokens reads a comma separated list of "token:subject[:admin]" entries.
+func ParseTokens(spec string) (map[string]Principal, error) {
+	tokens := make(map[string]Principal)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid API token entry %q", entry)
+		}
+		principal := Principal{Subject: parts[1]}
+		if len(parts) == 3 {
+			if parts[2] != "admin" {
+				return nil, fmt.Errorf("unknown role %q for subject %s", parts[2], parts[1])
+			}
+			principal.Admin = true
+		}
+		tokens[parts[0]] = principal
+	}
+	return tokens, nil
+}
+
+// Middleware resolves the bearer token of the request. Requests without a
+// token stay anonymous, requests with an unknown token are rejected.
+func Middleware(tokens map[string]Principal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok, err := Authenticate(tokens, c.GetHeader("Authorization"))
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if ok {
+			c.Request = c.Request.WithContext(NewContext(c.Request.Context(), principal))
+		}
+		c.Next()
+	}
+}
+
+// Authenticate resolves an Authorization header value. An empty header is
+// anonymous and ok is false; an unknown token is an UnauthorizedError.
+func Authenticate(tokens map[string]Principal, header string) (principal Principal, ok bool, err error) {
+	if header == "" {
+		return Principal{}, false, nil
+	}
+
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	principal, known := tokens[strings.TrimSpace(token)]
+	if !ok || !known {
+		return Principal{}, false, &apperrors.UnauthorizedError{Message: "Invalid API token"}
+	}
+	return principal, true, nil
+}
+
+// NewContext returns a copy of ctx carrying the authenticated principal.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated principal, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+func IsAdmin(ctx context.Context) bool {
+	principal, ok := FromContext(ctx)
+	return ok && principal.Admin
+}
+
+// RequireAdmin rejects requests that were not made with an admin token.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(c.Request.Context()) {
+			c.Error(&apperrors.ForbiddenError{Message: "Admin privileges required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,522 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"time-tracker/internal/auth"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the service. Values are resolved with the
+// following precedence, highest first:
+//
+//  1. process environment
+//  2. the .env file (ENV_FILE, ".env" by default)
+//  3. the YAML file named by CONFIG_FILE, if any
+//  4. built-in defaults
+type Config struct {
+	HTTP        HTTPConfig        `yaml:"http"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Log         LogConfig         `yaml:"log"`
+	PeopleInfo  PeopleInfoConfig  `yaml:"peopleInfo"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Health      HealthConfig      `yaml:"health"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	RateLimit   RateLimitConfig   `yaml:"rateLimit"`
+	Webhooks    WebhookConfig     `yaml:"webhooks"`
+	Outbox      OutboxConfig      `yaml:"outbox"`
+	Stream      StreamConfig      `yaml:"stream"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	GraphQL     GraphQLConfig     `yaml:"graphql"`
+}
+
+type HTTPConfig struct {
+	Addr              string        `yaml:"addr" env:"HTTP_ADDR"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"maxHeaderBytes" env:"HTTP_MAX_HEADER_BYTES"`
+	// ShutdownTimeout bounds how long in-flight requests and background
+	// workers are waited for after SIGINT/SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies is a comma separated list of proxy addresses or CIDRs
+	// whose X-Forwarded-For header is believed; empty trusts none.
+	TrustedProxies string `yaml:"trustedProxies" env:"HTTP_TRUSTED_PROXIES"`
+	// TLS is enabled when both files are set; they are re-read on SIGHUP.
+	TLSCertFile string `yaml:"tlsCertFile" env:"HTTP_TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tlsKeyFile" env:"HTTP_TLS_KEY_FILE"`
+}
+
+func (c HTTPConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" env:"POSTGRES_HOST"`
+	Port     int    `yaml:"port" env:"POSTGRES_PORT"`
+	User     string `yaml:"user" env:"POSTGRES_USER"`
+	Password string `yaml:"password" env:"POSTGRES_PASSWORD"`
+	Name     string `yaml:"name" env:"POSTGRES_NAME"`
+	SSLMode  string `yaml:"sslMode" env:"POSTGRES_SSLMODE"`
+	// MigrateOnStart applies pending migrations before serving requests.
+	MigrateOnStart bool `yaml:"migrateOnStart" env:"MIGRATE_ON_START"`
+}
+
+// DSN builds the pgx connection string.
+func (c DatabaseConfig) DSN() string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:   c.Name,
+	}
+	if c.SSLMode != "" {
+		dsn.RawQuery = "sslmode=" + url.QueryEscape(c.SSLMode)
+	}
+	return dsn.String()
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	// File is optional; logs go to stderr when it is empty. The file is
+	// rotated once it reaches MaxSizeMB.
+	File       string `yaml:"file" env:"LOG_FILE"`
+	MaxSizeMB  int    `yaml:"maxSizeMB" env:"LOG_MAX_SIZE_MB"`
+	MaxBackups int    `yaml:"maxBackups" env:"LOG_MAX_BACKUPS"`
+	MaxAgeDays int    `yaml:"maxAgeDays" env:"LOG_MAX_AGE_DAYS"`
+	// Debug records with the same message are sampled after SampleInitial
+	// per second, keeping every SampleThereafter-th; 0 disables sampling.
+	SampleInitial    int `yaml:"sampleInitial" env:"LOG_SAMPLE_INITIAL"`
+	SampleThereafter int `yaml:"sampleThereafter" env:"LOG_SAMPLE_THEREAFTER"`
+}
+
+type PeopleInfoConfig struct {
+	// URL of the People info API /info endpoint; enrichment is disabled when empty.
+	URL     string        `yaml:"url" env:"PEOPLE_INFO_URL"`
+	Timeout time.Duration `yaml:"timeout" env:"PEOPLE_INFO_TIMEOUT"`
+}
+
+type AuthConfig struct {
+	// Tokens is a comma separated list of "token:subject[:admin]" entries.
+	Tokens string `yaml:"tokens" env:"API_TOKENS"`
+	// StrictPreconditions makes If-Match mandatory on PUT, PATCH and DELETE.
+	StrictPreconditions bool `yaml:"strictPreconditions" env:"STRICT_PRECONDITIONS"`
+}
+
+type RetentionConfig struct {
+	SoftDelete    time.Duration `yaml:"softDelete" env:"SOFT_DELETE_RETENTION"`
+	PurgeInterval time.Duration `yaml:"purgeInterval" env:"PURGE_INTERVAL"`
+}
+
+type HealthConfig struct {
+	// CacheTTL is how long readiness check results are reused.
+	CacheTTL     time.Duration `yaml:"cacheTTL" env:"READINESS_CACHE_TTL"`
+	CheckTimeout time.Duration `yaml:"checkTimeout" env:"READINESS_CHECK_TIMEOUT"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, otlp or stdout.
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	// Endpoint is the OTLP/HTTP collector address, e.g. localhost:4318.
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	Insecure    bool    `yaml:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	ServiceName string  `yaml:"serviceName" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sampleRatio" env:"OTEL_TRACES_SAMPLER_ARG"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long the response to a request with an Idempotency-Key is kept.
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL"`
+	// LockTimeout is how long a retry waits for the first request before its
+	// reservation is taken over; it should exceed HTTP_WRITE_TIMEOUT.
+	LockTimeout time.Duration `yaml:"lockTimeout" env:"IDEMPOTENCY_LOCK_TIMEOUT"`
+}
+
+// RateLimitConfig sets the token buckets of the route groups: Rate requests
+// per second on average with bursts of up to Burst requests. A rate of 0
+// disables the limit of a group.
+type RateLimitConfig struct {
+	// Backend is memory, postgres or none; postgres shares the buckets
+	// between instances.
+	Backend     string  `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+	ReadRate    float64 `yaml:"readRate" env:"RATE_LIMIT_READ_RATE"`
+	ReadBurst   int     `yaml:"readBurst" env:"RATE_LIMIT_READ_BURST"`
+	WriteRate   float64 `yaml:"writeRate" env:"RATE_LIMIT_WRITE_RATE"`
+	WriteBurst  int     `yaml:"writeBurst" env:"RATE_LIMIT_WRITE_BURST"`
+	SearchRate  float64 `yaml:"searchRate" env:"RATE_LIMIT_SEARCH_RATE"`
+	SearchBurst int     `yaml:"searchBurst" env:"RATE_LIMIT_SEARCH_BURST"`
+}
+
+type WebhookConfig struct {
+	// PollInterval is how often due deliveries are looked up.
+	PollInterval time.Duration `yaml:"pollInterval" env:"WEBHOOK_POLL_INTERVAL"`
+	BatchSize    int           `yaml:"batchSize" env:"WEBHOOK_BATCH_SIZE"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	// Failed attempts are retried after RetryBase, doubling up to RetryMax,
+	// until MaxAttempts is reached.
+	MaxAttempts int           `yaml:"maxAttempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	RetryBase   time.Duration `yaml:"retryBase" env:"WEBHOOK_RETRY_BASE"`
+	RetryMax    time.Duration `yaml:"retryMax" env:"WEBHOOK_RETRY_MAX"`
+	// LogRetention is how long finished deliveries stay in the delivery log.
+	LogRetention time.Duration `yaml:"logRetention" env:"WEBHOOK_LOG_RETENTION"`
+}
+
+type OutboxConfig struct {
+	// Sinks is a comma separated list of webhooks, nats and stdout.
+	Sinks        string        `yaml:"sinks" env:"OUTBOX_SINKS"`
+	PollInterval time.Duration `yaml:"pollInterval" env:"OUTBOX_POLL_INTERVAL"`
+	BatchSize    int           `yaml:"batchSize" env:"OUTBOX_BATCH_SIZE"`
+	// Retention is how long published events are kept.
+	Retention time.Duration `yaml:"retention" env:"OUTBOX_RETENTION"`
+
+	NATSURL           string        `yaml:"natsURL" env:"NATS_URL"`
+	NATSSubjectPrefix string        `yaml:"natsSubjectPrefix" env:"NATS_SUBJECT_PREFIX"`
+	NATSTimeout       time.Duration `yaml:"natsTimeout" env:"NATS_TIMEOUT"`
+}
+
+type GRPCConfig struct {
+	// Addr is the listen address of the gRPC API; empty disables it. It
+	// uses the TLS certificate of the HTTP server when one is configured.
+	Addr string `yaml:"addr" env:"GRPC_ADDR"`
+	// ShutdownTimeout bounds how long in-flight calls are waited for after
+	// SIGINT/SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"GRPC_SHUTDOWN_TIMEOUT"`
+}
+
+type GraphQLConfig struct {
+	// Dev enables introspection and serves GraphiQL on GET /graphql.
+	Dev bool `yaml:"dev" env:"GRAPHQL_DEV"`
+	// ComplexityLimit rejects queries expected to resolve more fields than
+	// this, counting every item of a list.
+	ComplexityLimit int `yaml:"complexityLimit" env:"GRAPHQL_COMPLEXITY_LIMIT"`
+}
+
+type StreamConfig struct {
+	// TickInterval is how often live timer clients get the elapsed time of
+	// the running tasks.
+	TickInterval time.Duration `yaml:"tickInterval" env:"STREAM_TICK_INTERVAL"`
+	// MaxUsers bounds the number of users one client can follow.
+	MaxUsers int `yaml:"maxUsers" env:"STREAM_MAX_USERS"`
+}
+
+// SinkList returns the configured sinks.
+func (c OutboxConfig) SinkList() []string {
+	var sinks []string
+	for _, sink := range strings.Split(c.Sinks, ",") {
+		if sink = strings.ToLower(strings.TrimSpace(sink)); sink != "" {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+func defaults() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:           "localhost",
+			Port:           5432,
+			User:           "postgres",
+			Name:           "time_tracker",
+			MigrateOnStart: true,
+		},
+		Log: LogConfig{
+			Level:            "debug",
+			Format:           "json",
+			MaxSizeMB:        100,
+			MaxBackups:       5,
+			MaxAgeDays:       28,
+			SampleInitial:    100,
+			SampleThereafter: 100,
+		},
+		PeopleInfo: PeopleInfoConfig{
+			Timeout: 2 * time.Second,
+		},
+		Retention: RetentionConfig{
+			SoftDelete:    30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Health: HealthConfig{
+			CacheTTL:     5 * time.Second,
+			CheckTimeout: 2 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "time-tracker",
+			SampleRatio: 1,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         24 * time.Hour,
+			LockTimeout: time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Backend:     "memory",
+			ReadRate:    20,
+			ReadBurst:   40,
+			WriteRate:   5,
+			WriteBurst:  10,
+			SearchRate:  2,
+			SearchBurst: 5,
+		},
+		Webhooks: WebhookConfig{
+			PollInterval: 5 * time.Second,
+			BatchSize:    20,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			RetryBase:    10 * time.Second,
+			RetryMax:     time.Hour,
+			LogRetention: 30 * 24 * time.Hour,
+		},
+		Outbox: OutboxConfig{
+			Sinks:             "webhooks",
+			PollInterval:      time.Second,
+			BatchSize:         100,
+			Retention:         24 * time.Hour,
+			NATSURL:           "nats://localhost:4222",
+			NATSSubjectPrefix: "time-tracker",
+			NATSTimeout:       5 * time.Second,
+		},
+		Stream: StreamConfig{
+			TickInterval: 5 * time.Second,
+			MaxUsers:     100,
+		},
+		GRPC: GRPCConfig{
+			Addr:            ":9090",
+			ShutdownTimeout: 20 * time.Second,
+		},
+		GraphQL: GraphQLConfig{
+			ComplexityLimit: 2000,
+		},
+	}
+}
+
+// Load resolves the configuration and validates it. All problems are
+// reported at once.
+func Load() (*Config, error) {
+	cfg := defaults()
+	var problems Errors
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	envFile := os.Getenv("ENV_FILE")
+	if envFile == "" {
+		envFile = ".env"
+	}
+	dotenv, err := godotenv.Read(envFile)
+	if err != nil && !(errors.Is(err, os.ErrNotExist) && os.Getenv("ENV_FILE") == "") {
+		return nil, fmt.Errorf("read env file: %w", err)
+	}
+
+	lookup := func(key string) (string, bool) {
+		if val, ok := os.LookupEnv(key); ok {
+			return val, true
+		}
+		val, ok := dotenv[key]
+		return val, ok
+	}
+	problems = append(problems, applyEnv(reflect.ValueOf(&cfg).Elem(), lookup)...)
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return &cfg, nil
+}
+
+// applyEnv overrides every field tagged with env that has a value.
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) Errors {
+	var problems Errors
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			problems = append(problems, applyEnv(field, lookup)...)
+			continue
+		}
+
+		key := v.Type().Field(i).Tag.Get("env")
+		raw, ok := lookup(key)
+		if key == "" || !ok {
+			continue
+		}
+
+		switch {
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid duration %q", key, raw))
+				continue
+			}
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid integer %q", key, raw))
+				continue
+			}
+			field.SetInt(int64(n))
+		case field.Kind() == reflect.Float64:
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid number %q", key, raw))
+				continue
+			}
+			field.SetFloat(f)
+		case field.Kind() == reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid boolean %q", key, raw))
+				continue
+			}
+			field.SetBool(b)
+		default:
+			field.SetString(raw)
+		}
+	}
+	return problems
+}
+
+func (c *Config) validate() Errors {
+	var problems Errors
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "HTTP_ADDR must not be empty")
+	check(c.HTTP.ReadTimeout > 0, "HTTP_READ_TIMEOUT must be positive")
+	check(c.HTTP.ReadHeaderTimeout > 0, "HTTP_READ_HEADER_TIMEOUT must be positive")
+	check(c.HTTP.WriteTimeout > 0, "HTTP_WRITE_TIMEOUT must be positive")
+	check(c.HTTP.IdleTimeout > 0, "HTTP_IDLE_TIMEOUT must be positive")
+	check(c.HTTP.MaxHeaderBytes >= 4096, "HTTP_MAX_HEADER_BYTES must be at least 4096, got %d", c.HTTP.MaxHeaderBytes)
+	check(c.HTTP.ShutdownTimeout > 0, "HTTP_SHUTDOWN_TIMEOUT must be positive")
+	check((c.HTTP.TLSCertFile == "") == (c.HTTP.TLSKeyFile == ""), "HTTP_TLS_CERT_FILE and HTTP_TLS_KEY_FILE must be set together")
+
+	check(c.Database.Host != "", "POSTGRES_HOST must not be empty")
+	check(c.Database.Port > 0 && c.Database.Port < 65536, "POSTGRES_PORT must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.User != "", "POSTGRES_USER must not be empty")
+	check(c.Database.Name != "", "POSTGRES_NAME must not be empty")
+	check(oneOf(c.Database.SSLMode, "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"POSTGRES_SSLMODE has unknown value %q", c.Database.SSLMode)
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "warning", "error"), "LOG_LEVEL has unknown value %q", c.Log.Level)
+	check(oneOf(c.Log.Format, "json", "text"), "LOG_FORMAT must be json or text, got %q", c.Log.Format)
+	check(c.Log.MaxSizeMB > 0, "LOG_MAX_SIZE_MB must be positive")
+	check(c.Log.MaxBackups >= 0, "LOG_MAX_BACKUPS must not be negative")
+	check(c.Log.MaxAgeDays >= 0, "LOG_MAX_AGE_DAYS must not be negative")
+	check(c.Log.SampleInitial >= 0 && c.Log.SampleThereafter >= 0, "LOG_SAMPLE_INITIAL and LOG_SAMPLE_THEREAFTER must not be negative")
+
+	if c.PeopleInfo.URL != "" {
+		u, err := url.Parse(c.PeopleInfo.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "PEOPLE_INFO_URL must be an absolute http(s) URL, got %q", c.PeopleInfo.URL)
+	}
+	check(c.PeopleInfo.Timeout > 0, "PEOPLE_INFO_TIMEOUT must be positive")
+
+	if _, err := auth.ParseTokens(c.Auth.Tokens); err != nil {
+		problems = append(problems, "API_TOKENS: "+err.Error())
+	}
+
+	check(c.Retention.SoftDelete > 0, "SOFT_DELETE_RETENTION must be positive")
+	check(c.Retention.PurgeInterval > 0, "PURGE_INTERVAL must be positive")
+
+	check(c.Health.CacheTTL >= 0, "READINESS_CACHE_TTL must not be negative")
+	check(c.Health.CheckTimeout > 0, "READINESS_CHECK_TIMEOUT must be positive")
+
+	check(oneOf(c.Tracing.Exporter, "none", "otlp", "stdout"), "OTEL_TRACES_EXPORTER must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "OTEL_EXPORTER_OTLP_ENDPOINT is required for the otlp exporter")
+	check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME must not be empty")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "OTEL_TRACES_SAMPLER_ARG must be between 0 and 1")
+
+	check(c.Idempotency.TTL > 0, "IDEMPOTENCY_TTL must be positive")
+	check(c.Idempotency.LockTimeout > 0, "IDEMPOTENCY_LOCK_TIMEOUT must be positive")
+
+	check(oneOf(c.RateLimit.Backend, "none", "memory", "postgres"), "RATE_LIMIT_BACKEND must be none, memory or postgres, got %q", c.RateLimit.Backend)
+	limits := []struct {
+		name  string
+		rate  float64
+		burst int
+	}{
+		{"READ", c.RateLimit.ReadRate, c.RateLimit.ReadBurst},
+		{"WRITE", c.RateLimit.WriteRate, c.RateLimit.WriteBurst},
+		{"SEARCH", c.RateLimit.SearchRate, c.RateLimit.SearchBurst},
+	}
+	for _, l := range limits {
+		check(l.rate >= 0, "RATE_LIMIT_%s_RATE must not be negative", l.name)
+		check(l.rate == 0 || l.burst > 0, "RATE_LIMIT_%s_BURST must be positive", l.name)
+	}
+
+	check(c.Webhooks.PollInterval > 0, "WEBHOOK_POLL_INTERVAL must be positive")
+	check(c.Webhooks.BatchSize > 0, "WEBHOOK_BATCH_SIZE must be positive")
+	check(c.Webhooks.Timeout > 0, "WEBHOOK_TIMEOUT must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive")
+	check(c.Webhooks.RetryBase > 0 && c.Webhooks.RetryMax >= c.Webhooks.RetryBase, "WEBHOOK_RETRY_BASE must be positive and not exceed WEBHOOK_RETRY_MAX")
+	check(c.Webhooks.LogRetention > 0, "WEBHOOK_LOG_RETENTION must be positive")
+
+	for _, sink := range c.Outbox.SinkList() {
+		check(oneOf(sink, "webhooks", "nats", "stdout"), "OUTBOX_SINKS has unknown sink %q", sink)
+		if sink == "nats" {
+			check(c.Outbox.NATSURL != "", "NATS_URL is required for the nats sink")
+			check(c.Outbox.NATSSubjectPrefix != "", "NATS_SUBJECT_PREFIX must not be empty")
+			check(c.Outbox.NATSTimeout > 0, "NATS_TIMEOUT must be positive")
+		}
+	}
+	check(c.Outbox.PollInterval > 0, "OUTBOX_POLL_INTERVAL must be positive")
+	check(c.Outbox.BatchSize > 0, "OUTBOX_BATCH_SIZE must be positive")
+	check(c.Outbox.Retention > 0, "OUTBOX_RETENTION must be positive")
+
+	check(c.Stream.TickInterval > 0, "STREAM_TICK_INTERVAL must be positive")
+	check(c.Stream.MaxUsers > 0, "STREAM_MAX_USERS must be positive")
+
+	check(c.GRPC.ShutdownTimeout > 0, "GRPC_SHUTDOWN_TIMEOUT must be positive")
+
+	check(c.GraphQL.ComplexityLimit > 0, "GRAPHQL_COMPLEXITY_LIMIT must be positive")
+
+	return problems
+}
+
+func oneOf(val string, allowed ...string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(val, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors aggregates every configuration problem found by Load.
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e, "\n  - ")
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AuditController struct {
+	auditRepo *db.AuditRepository
+}
+
+func NewAuditController(auditRepo *db.AuditRepository) *AuditController {
+	return &AuditController{auditRepo: auditRepo}
+}
+
+// @Summary     List audit events
+// @Description List audit events with filtering by entity, actor and time range
+// @Tags        audit
+// @Produce     json
+// @Param       entityType query    string false "Entity type (user, task)"
+// @Param       entityId   query    int    false "Entity ID"
+// @Param       actor      query    string false "Actor"
+// @Param       action     query    string false "Action"
+// @Param       from       query    string false "Start of the time range in RFC3339 format"
+// @Param       to         query    string false "End of the time range in RFC3339 format"
+// @Param       limit      query    int    false "Page size"
+// @Param       offset     query    int    false "Offset"
+// @Success     200        {array}  models.AuditEvent
+// @Failure     400        {object} problem.Problem
+// @Failure     403        {object} problem.Problem
+// @Failure     500        {object} problem.Problem
+// @Router      /audit [get]
+func (ac *AuditController) GetEvents(c *gin.Context) {
+	filter := models.AuditFilter{
+		EntityType: c.Query("entityType"),
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		Limit:      defaultAuditLimit,
+	}
+
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"entityId", &filter.EntityID},
+		{"limit", &filter.Limit},
+		{"offset", &filter.Offset},
+	}
+	for _, param := range ints {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		val, err := strconv.Atoi(raw)
+		if err != nil || val < 0 {
+			logger.FromContext(c).Error("Invalid audit filter value",
+				param.name, raw,
+				"error", err,
+			)
+			c.Error(&apperrors.BadRequestError{Message: "Invalid " + param.name + " value"})
+			return
+		}
+		*param.dst = val
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	}
+	for _, param := range times {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		val, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			logger.FromContext(c).Error("Invalid audit time range",
+				param.name, raw,
+				"error", err,
+			)
+			c.Error(&apperrors.BadRequestError{Message: "Invalid " + param.name + " time"})
+			return
+		}
+		*param.dst = val
+	}
+
+	events, err := ac.auditRepo.GetEvents(c, filter)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to get audit events",
+			"filter", filter,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"time-tracker/internal/health"
+	"time-tracker/internal/jobs"
+	"time-tracker/internal/stream"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HealthController struct {
+	checker  *health.Checker
+	pool     *pgxpool.Pool
+	purger   *jobs.Purger
+	webhooks *jobs.Dispatcher
+	relay    *jobs.Relay
+	hub      *stream.Hub
+	started  time.Time
+	build    health.BuildInfo
+}
+
+func NewHealthController(checker *health.Checker, pool *pgxpool.Pool, purger *jobs.Purger, webhooks *jobs.Dispatcher, relay *jobs.Relay, hub *stream.Hub) *HealthController {
+	return &HealthController{
+		checker:  checker,
+		pool:     pool,
+		purger:   purger,
+		webhooks: webhooks,
+		relay:    relay,
+		hub:      hub,
+		started:  time.Now(),
+		build:    health.ReadBuildInfo(),
+	}
+}
+
+// @Summary     Liveness probe
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} map[string]string
+// @Router      /healthz [get]
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// @Summary     Readiness probe
+// @Description Checks the database, the schema version and the People info API
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} map[string]interface{}
+// @Failure     503 {object} map[string]interface{}
+// @Router      /readyz [get]
+func (hc *HealthController) Readiness(c *gin.Context) {
+	ready, results := hc.checker.Run(c)
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	// The errors are only shown on /debug/status.
+	for i := range results {
+		results[i].Error = ""
+	}
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
+
+type poolStats struct {
+	AcquireCount            int64  `json:"acquireCount"`
+	AcquireDuration         string `json:"acquireDuration"`
+	AcquiredConns           int32  `json:"acquiredConns"`
+	CanceledAcquireCount    int64  `json:"canceledAcquireCount"`
+	ConstructingConns       int32  `json:"constructingConns"`
+	EmptyAcquireCount       int64  `json:"emptyAcquireCount"`
+	IdleConns               int32  `json:"idleConns"`
+	MaxConns                int32  `json:"maxConns"`
+	TotalConns              int32  `json:"totalConns"`
+	NewConnsCount           int64  `json:"newConnsCount"`
+	MaxLifetimeDestroyCount int64  `json:"maxLifetimeDestroyCount"`
+	MaxIdleDestroyCount     int64  `json:"maxIdleDestroyCount"`
+}
+
+// @Summary     Diagnostics
+// @Description Build info, uptime, readiness checks with their errors, connection pool statistics and background job status. Requires an admin token.
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} map[string]interface{}
+// @Failure     401 {object} map[string]string
+// @Failure     403 {object} map[string]string
+// @Router      /debug/status [get]
+func (hc *HealthController) Status(c *gin.Context) {
+	stat := hc.pool.Stat()
+	ready, checks := hc.checker.Run(c)
+	c.JSON(http.StatusOK, gin.H{
+		"build":     hc.build,
+		"startedAt": hc.started,
+		"uptime":    time.Since(hc.started).Round(time.Second).String(),
+		"ready":     ready,
+		"checks":    checks,
+		"pool": poolStats{
+			AcquireCount:            stat.AcquireCount(),
+			AcquireDuration:         stat.AcquireDuration().String(),
+			AcquiredConns:           stat.AcquiredConns(),
+			CanceledAcquireCount:    stat.CanceledAcquireCount(),
+			ConstructingConns:       stat.ConstructingConns(),
+			EmptyAcquireCount:       stat.EmptyAcquireCount(),
+			IdleConns:               stat.IdleConns(),
+			MaxConns:                stat.MaxConns(),
+			TotalConns:              stat.TotalConns(),
+			NewConnsCount:           stat.NewConnsCount(),
+			MaxLifetimeDestroyCount: stat.MaxLifetimeDestroyCount(),
+			MaxIdleDestroyCount:     stat.MaxIdleDestroyCount(),
+		},
+		"jobs": []interface{}{hc.purger.Status(), hc.webhooks.Status(), hc.relay.Status(), hc.hub.Status()},
+	})
+}
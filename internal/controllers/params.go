@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"strconv"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/etag"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// includeDeleted reads the includeDeleted query flag. Soft-deleted rows are
+// only visible to admins; on a bad or forbidden flag the error is attached
+// to c and ok is false.
+func includeDeleted(c *gin.Context) (include bool, ok bool) {
+	raw := c.Query("includeDeleted")
+	if raw == "" {
+		return false, true
+	}
+
+	include, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.Error(&apperrors.BadRequestError{Message: "Invalid includeDeleted value"})
+		return false, false
+	}
+	if include && !auth.IsAdmin(c.Request.Context()) {
+		c.Error(&apperrors.ForbiddenError{Message: "Only admins can see deleted records"})
+		return false, false
+	}
+	return include, true
+}
+
+// precondition parses the If-Match header. On a malformed header the error is
+// attached to c and ok is false.
+func precondition(c *gin.Context) (cond models.Precondition, ok bool) {
+	cond, err := etag.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.Error(&apperrors.BadRequestError{Message: "Invalid If-Match header"})
+		return cond, false
+	}
+	return cond, true
+}
+
+// language is the language validation messages are rendered in.
+func language(c *gin.Context) validation.Lang {
+	return validation.Language(c.GetHeader("Accept-Language"))
+}
+
+// bindJSON decodes and validates the request body into obj. On failure the
+// localized error listing every invalid field is attached to c and ok is
+// false.
+func bindJSON(c *gin.Context, obj interface{}) (ok bool) {
+	return bindWith(c, obj, c.ShouldBindJSON)
+}
+
+// bindQuery is bindJSON for query parameters.
+func bindQuery(c *gin.Context, obj interface{}) (ok bool) {
+	return bindWith(c, obj, c.ShouldBindQuery)
+}
+
+func bindWith(c *gin.Context, obj interface{}, bind func(interface{}) error) bool {
+	if err := bind(obj); err != nil {
+		logger.FromContext(c).Info("Invalid request",
+			"error", err,
+		)
+		c.Error(validation.Translate(err, language(c)))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"time-tracker/internal/apperrors"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	maxSearchLength    = 200
+)
+
+type SearchController struct {
+	searchRepo *db.SearchRepository
+}
+
+func NewSearchController(searchRepo *db.SearchRepository) *SearchController {
+	return &SearchController{searchRepo: searchRepo}
+}
+
+// @Summary     Search users and tasks
+// @Description Full-text search over task descriptions and user names and addresses with typo-tolerant name matching
+// @Tags        search
+// @Produce     json
+// @Param       q     query    string true  "Search text"
+// @Param       type  query    string false "Restrict results to users or tasks"
+// @Param       limit query    int    false "Maximum number of hits per entity type"
+// @Success     200   {object} models.SearchResults
+// @Failure     400   {object} problem.Problem
+// @Failure     500   {object} problem.Problem
+// @Router      /search [get]
+func (sc *SearchController) Search(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" || utf8.RuneCountInString(text) > maxSearchLength {
+		c.Error(&apperrors.BadRequestError{Message: "Search text must be between 1 and 200 characters long"})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.Error(&apperrors.BadRequestError{Message: "Invalid limit value"})
+			return
+		}
+		if parsed > maxSearchLimit {
+			parsed = maxSearchLimit
+		}
+		limit = parsed
+	}
+
+	entity := c.Query("type")
+	if entity != "" && entity != "users" && entity != "tasks" {
+		c.Error(&apperrors.BadRequestError{Message: "Invalid type value"})
+		return
+	}
+
+	results := models.SearchResults{Query: text, Users: []models.UserSearchHit{}, Tasks: []models.TaskSearchHit{}}
+	var err error
+	if entity != "tasks" {
+		if results.Users, err = sc.searchRepo.SearchUsers(c, text, limit); err != nil {
+			sc.searchFailed(c, text, err)
+			return
+		}
+	}
+	if entity != "users" {
+		if results.Tasks, err = sc.searchRepo.SearchTasks(c, text, limit); err != nil {
+			sc.searchFailed(c, text, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func (sc *SearchController) searchFailed(c *gin.Context, text string, err error) {
+	logger.FromContext(c).Error("Search failed",
+		"query", text,
+		"error", err,
+	)
+	c.Error(err)
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/metrics"
+	"time-tracker/internal/models"
+	"time-tracker/internal/stream"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamWriteWait bounds every write to a live timer client; a client
+	// that doesn't read for that long is disconnected.
+	streamWriteWait = 10 * time.Second
+	// sseRetry is the reconnection delay suggested to SSE clients.
+	sseRetry = 5 * time.Second
+)
+
+type StreamController struct {
+	hub      *stream.Hub
+	maxUsers int
+	upgrader websocket.Upgrader
+}
+
+func NewStreamController(hub *stream.Hub, maxUsers int) *StreamController {
+	return &StreamController{
+		hub:      hub,
+		maxUsers: maxUsers,
+		upgrader: websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024},
+	}
+}
+
+// @Summary     Live timer updates (SSE)
+// @Description Server-Sent Events stream of the running tasks of the followed users. The stream starts with a "snapshot"
+// @Description of the running tasks, followed by task.started, task.ended and user.deleted events as they are committed on
+// @Description any instance, and "tick" messages with the elapsed time of the running tasks. A new snapshot is sent whenever
+// @Description events may have been missed. Every message is a models.StreamMessage named after its type. Tasks have no
+// @Description paused state, so there is no task.paused event: a task runs from task.started until task.ended.
+// @Tags        stream
+// @Produce     text/event-stream
+// @Param       users query    string false "Comma separated IDs of the users to follow, e.g. the members of a team; all users when empty (admin only)"
+// @Success     200   {object} models.StreamMessage
+// @Failure     400   {object} problem.Problem
+// @Failure     403   {object} problem.Problem
+// @Failure     429   {object} problem.Problem
+// @Failure     500   {object} problem.Problem
+// @Router      /stream [get]
+func (sc *StreamController) Events(c *gin.Context) {
+	session, ok := sc.open(c)
+	if !ok {
+		return
+	}
+	defer session.Close()
+
+	metrics.StreamOpened("sse")
+	defer metrics.StreamClosed("sse")
+
+	// The write deadline of the server is replaced by one per message.
+	rc := http.NewResponseController(c.Writer)
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// Keeps reverse proxies such as nginx from buffering the stream.
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	write := func(frame []byte) error {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		if _, err := c.Writer.Write(frame); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	err := write([]byte(fmt.Sprintf("retry: %d\n\n", sseRetry.Milliseconds())))
+	if err == nil {
+		err = session.Run(c.Request.Context(), func(msg models.StreamMessage) error {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			var frame bytes.Buffer
+			if msg.EventID != "" {
+				fmt.Fprintf(&frame, "id: %s\n", msg.EventID)
+			}
+			fmt.Fprintf(&frame, "event: %s\ndata: %s\n\n", msg.Type, data)
+			return write(frame.Bytes())
+		})
+	}
+	if err != nil {
+		logger.FromContext(c).Info("Live timer stream closed",
+			"transport", "sse",
+			"error", err,
+		)
+	}
+}
+
+// @Summary     Live timer updates (WebSocket)
+// @Description WebSocket carrying the same messages as GET /stream as JSON text frames. Messages sent by the client are ignored.
+// @Tags        stream
+// @Param       users query    string false "Comma separated IDs of the users to follow, e.g. the members of a team; all users when empty (admin only)"
+// @Success     101   {object} models.StreamMessage
+// @Failure     400   {object} problem.Problem
+// @Failure     403   {object} problem.Problem
+// @Failure     429   {object} problem.Problem
+// @Failure     500   {object} problem.Problem
+// @Router      /stream/ws [get]
+func (sc *StreamController) WebSocket(c *gin.Context) {
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		c.Error(&apperrors.BadRequestError{Message: "Expected a WebSocket upgrade request"})
+		return
+	}
+	session, ok := sc.open(c)
+	if !ok {
+		return
+	}
+	defer session.Close()
+
+	// The upgrader replies to a failed handshake itself.
+	conn, err := sc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.FromContext(c).Info("WebSocket handshake failed",
+			"error", err,
+		)
+		return
+	}
+	defer conn.Close()
+
+	metrics.StreamOpened("websocket")
+	defer metrics.StreamClosed("websocket")
+
+	// The request context isn't cancelled when a hijacked client goes away,
+	// so a reader notices that. It also answers pings and close frames.
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	conn.SetReadLimit(512)
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = session.Run(ctx, func(msg models.StreamMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		return conn.WriteJSON(msg)
+	})
+	if err != nil {
+		logger.FromContext(c).Info("Live timer stream closed",
+			"transport", "websocket",
+			"error", err,
+		)
+	}
+	if ctx.Err() == nil {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(streamWriteWait))
+	}
+}
+
+// open parses the followed users and opens a session. On failure the error
+// is attached to c and ok is false.
+func (sc *StreamController) open(c *gin.Context) (session *stream.Session, ok bool) {
+	var users []int
+	for _, raw := range strings.Split(c.Query("users"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			c.Error(&apperrors.BadRequestError{Message: fmt.Sprintf("Invalid user ID %q", raw)})
+			return nil, false
+		}
+		users = append(users, id)
+	}
+	if len(users) > sc.maxUsers {
+		c.Error(&apperrors.BadRequestError{Message: fmt.Sprintf("At most %d users can be followed", sc.maxUsers)})
+		return nil, false
+	}
+	if len(users) == 0 && !auth.IsAdmin(c.Request.Context()) {
+		c.Error(&apperrors.ForbiddenError{Message: "Only admins can follow every user"})
+		return nil, false
+	}
+
+	session, err := sc.hub.Open(c, users)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+	return session, true
+}
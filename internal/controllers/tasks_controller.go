@@ -1,23 +1,25 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	db "time-tracker/internal/database"
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/etag"
 	"time-tracker/internal/logger"
 	"time-tracker/internal/models"
+	"time-tracker/internal/storage"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 type TaskController struct {
-	taskRepo *db.TaskRepository
+	taskRepo storage.TaskRepository
 }
 
-func NewTaskController(taskRepo *db.TaskRepository) *TaskController {
+func NewTaskController(taskRepo storage.TaskRepository) *TaskController {
 	return &TaskController{taskRepo: taskRepo}
 }
 
@@ -30,59 +32,102 @@ func NewTaskController(taskRepo *db.TaskRepository) *TaskController {
 // @Param       userID path     int    true "User ID"
 // @Param       start  query    string true "Start time in RFC3339 format"
 // @Param       end    query    string true "End time in RFC3339 format"
+// @Param       includeDeleted query bool false "Include soft-deleted tasks (admin only)"
+// @Param       Accept-Language header string false "Language of validation messages (en, ru)"
 // @Success     200    {array}  models.Task
-// @Failure     400    {object} gin.H
-// @Failure     500    {object} gin.H
+// @Failure     400    {object} problem.Problem
+// @Failure     422    {object} problem.Problem
+// @Failure     500    {object} problem.Problem
 // @Router      /users/{userID}/tasks [get]
 func (tc *TaskController) GetUserTasksByPeriod(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("userID"))
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID": c.Param("userID"),
-			"error":  err,
-		}).Error("User ID is incorrect")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is incorrect"})
+		logger.FromContext(c).Error("User ID is incorrect",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "User ID is incorrect"})
 		return
 	}
 
-	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	var period models.PeriodQuery
+	if !bindQuery(c, &period) {
+		return
+	}
+	// Both bounds were checked by the rfc3339 rule.
+	start, _ := time.Parse(time.RFC3339, period.Start)
+	end, _ := time.Parse(time.RFC3339, period.End)
+
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+
+	tasks, err := tc.taskRepo.GetUserTasksByPeriod(c, userID, start, end, withDeleted)
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"start": c.Query("start"),
-			"error": err,
-		}).Error("Invalid start time")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start time"})
+		logger.FromContext(c).Error("Failed to get user tasks for the period",
+			"userID", c.Param("userID"),
+			"start", start,
+			"end", end,
+			"error", err,
+		)
+		c.Error(err)
 		return
 	}
+	logger.FromContext(c).Info("Successfully receiving user tasks for the period",
+		"userID", c.Param("userID"),
+		"start", start,
+		"end", end,
+	)
 
-	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	c.JSON(http.StatusOK, tasks)
+}
+
+// @Summary     Get a task
+// @Description Get a task by ID. Supports conditional requests with If-None-Match.
+// @Tags        tasks
+// @Produce     json
+// @Param       taskID         path     int    true  "Task ID"
+// @Param       includeDeleted query    bool   false "Include soft-deleted tasks (admin only)"
+// @Param       If-None-Match  header   string false "ETag of the cached version"
+// @Success     200            {object} models.Task
+// @Success     304
+// @Failure     400            {object} problem.Problem
+// @Failure     404            {object} problem.Problem
+// @Failure     500            {object} problem.Problem
+// @Router      /tasks/{taskID} [get]
+func (tc *TaskController) GetTask(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("taskID"))
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"end":   c.Query("end"),
-			"error": err,
-		}).Error("Invalid end time")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end time"})
+		logger.FromContext(c).Error("Invalid task ID",
+			"taskID", c.Param("taskID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid task ID"})
 		return
 	}
 
-	tasks, err := tc.taskRepo.GetUserTasksByPeriod(c, userID, start, end)
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+
+	task, err := tc.taskRepo.GetTaskByID(c, taskID, withDeleted)
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID": c.Param("userID"),
-			"start":  start,
-			"end":    end,
-			"error":  err,
-		}).Error("Failed to get user tasks for the period")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.FromContext(c).Error("Failed to get task",
+			"taskID", taskID,
+			"error", err,
+		)
+		c.Error(err)
 		return
 	}
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": c.Param("userID"),
-		"start":  start,
-		"end":    end,
-	}).Info("Successfully receiving user tasks for the period")
 
-	c.JSON(http.StatusOK, tasks)
+	c.Header("ETag", etag.Format(task.Version))
+	if etag.NotModified(c, task.Version) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, task)
 }
 
 // @Summary     Start a new task
@@ -91,36 +136,45 @@ func (tc *TaskController) GetUserTasksByPeriod(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       task body     models.Request true "Task to start"
+// @Param       Accept-Language header string false "Language of validation messages (en, ru)"
+// @Param       Idempotency-Key header string false "Key for safely retrying the request"
 // @Success     201  {object} models.Task
-// @Failure     400  {object} gin.H
-// @Failure     500  {object} gin.H
+// @Failure     400  {object} problem.Problem
+// @Failure     409  {object} problem.Problem
+// @Failure     422  {object} problem.Problem
+// @Failure     500  {object} problem.Problem
 // @Router      /tasks/start [post]
 func (tc *TaskController) StartTask(c *gin.Context) {
 	var req models.Request
 
-	if err := c.BindJSON(&req); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("Failed to bind model to data")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to bind model to data"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	if err := tc.taskRepo.StartTask(c, int(req.UserID), req.Description); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID":      int(req.UserID),
-			"description": req.Description,
-			"error":       err,
-		}).Error("Failed to start task")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	task, err := tc.taskRepo.StartTask(c, int(req.UserID), req.Description)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to start task",
+			"userID", int(req.UserID),
+			"description", req.Description,
+			"error", err,
+		)
+		// The user is part of the request body, so a missing one is the client's mistake.
+		var noUser *apperrors.NoUserError
+		if errors.As(err, &noUser) {
+			c.Error(&apperrors.BadRequestError{Message: noUser.Message})
+			return
+		}
+		c.Error(err)
 		return
 	}
 
-	logger.Logger.WithFields(logrus.Fields{
-		"userID":      int(req.UserID),
-		"description": req.Description,
-	}).Info("The task has been started")
-	c.JSON(http.StatusCreated, gin.H{"msg": "The task has been started"})
+	logger.FromContext(c).Info("The task has been started",
+		"userID", int(req.UserID),
+		"description", req.Description,
+		"taskID", task.ID,
+	)
+	c.Header("ETag", etag.Format(task.Version))
+	c.JSON(http.StatusCreated, task)
 }
 
 // @Summary     End a task
@@ -128,33 +182,46 @@ func (tc *TaskController) StartTask(c *gin.Context) {
 // @Tags        tasks
 // @Accept      json
 // @Produce     json
-// @Param       taskID path     int true "Task ID"
+// @Param       taskID   path     int    true  "Task ID"
+// @Param       If-Match header   string false "ETag of the task version being ended"
+// @Param       Idempotency-Key header string false "Key for safely retrying the request"
 // @Success     200    {object} models.Task
-// @Failure     400    {object} gin.H
-// @Failure     500    {object} gin.H
+// @Failure     400    {object} problem.Problem
+// @Failure     404    {object} problem.Problem
+// @Failure     409    {object} problem.Problem
+// @Failure     412    {object} problem.Problem
+// @Failure     422    {object} problem.Problem
+// @Failure     500    {object} problem.Problem
 // @Router      /tasks/end/{taskID} [post]
 func (tc *TaskController) EndTask(c *gin.Context) {
 	taskID, err := strconv.Atoi(c.Param("taskID"))
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"taskID": taskID,
-			"error":  err,
-		}).Error("Invalid task ID")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		logger.FromContext(c).Error("Invalid task ID",
+			"taskID", taskID,
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid task ID"})
 		return
 	}
 
-	if err := tc.taskRepo.EndTask(c, taskID); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"taskID": taskID,
-			"error":  err,
-		}).Error("Failed to finish task")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	cond, ok := precondition(c)
+	if !ok {
+		return
+	}
+
+	task, err := tc.taskRepo.EndTask(c, taskID, cond)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to finish task",
+			"taskID", taskID,
+			"error", err,
+		)
+		c.Error(err)
 		return
 	}
-	logger.Logger.WithFields(logrus.Fields{
-		"taskID": taskID,
-	}).Info("The task was over")
+	logger.FromContext(c).Info("The task was over",
+		"taskID", taskID,
+	)
 
-	c.JSON(http.StatusOK, gin.H{"msg": "The task was over"})
+	c.Header("ETag", etag.Format(task.Version))
+	c.JSON(http.StatusOK, task)
 }
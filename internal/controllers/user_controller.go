@@ -1,151 +1,414 @@
 package controllers
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
-	db "time-tracker/internal/database"
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/etag"
 	"time-tracker/internal/logger"
 	"time-tracker/internal/models"
+	"time-tracker/internal/peopleinfo"
+	"time-tracker/internal/storage"
+	"time-tracker/internal/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 type UserController struct {
-	userRepo *db.UserRepository
+	userRepo   storage.UserRepository
+	peopleInfo *peopleinfo.Client
 }
 
-func NewUserController(userRepo *db.UserRepository) *UserController {
-	return &UserController{userRepo: userRepo}
+func NewUserController(userRepo storage.UserRepository, peopleInfo *peopleinfo.Client) *UserController {
+	return &UserController{userRepo: userRepo, peopleInfo: peopleInfo}
 }
 
 func (uc *UserController) GetUsers(c *gin.Context) {
-	limit := 1
-	offset := 0
+	query, err := parseUserQuery(c.Request.URL.Query())
+	if err != nil {
+		logger.FromContext(c).Error("Invalid user query",
+			"query", c.Request.URL.RawQuery,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
 
-	if limitStr := c.Query("limit"); limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err != nil {
-			logger.Logger.WithFields(logrus.Fields{
-				"limit": limitStr,
-				"error": err,
-			}).Error("Invalid value of the specified limit")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
-			return
-		}
-		limit = parsedLimit
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
 	}
+	query.IncludeDeleted = withDeleted
 
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err != nil {
-			logger.Logger.WithFields(logrus.Fields{
-				"offset": offsetStr,
-				"error":  err,
-			}).Error("Invalid indent value specified")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset value"})
-			return
-		}
-		offset = parsedOffset
+	page, err := uc.userRepo.GetUsers(c, query)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to get user information",
+			"query", query,
+			"error", err,
+		)
+		c.Error(err)
+		return
 	}
 
-	filter := make(map[string]interface{})
-	allowedFilters := []string{"passport_number", "surname", "name", "patronymic", "address"}
+	c.Header("X-Total-Count", strconv.Itoa(page.Total))
+	if page.Next != "" {
+		next := *c.Request.URL
+		params := next.Query()
+		params.Set("cursor", page.Next)
+		next.RawQuery = params.Encode()
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	c.JSON(http.StatusOK, page)
+}
 
-	for _, filterReq := range allowedFilters {
-		if filterVal := c.Query(filterReq); filterVal != "" {
-			filter[filterReq] = filterVal
-		}
+func (uc *UserController) GetUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		logger.FromContext(c).Error("Invalid user ID",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid user ID"})
+		return
+	}
+
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
 	}
 
-	users, err := uc.userRepo.GetUsers(c, filter, limit, offset)
+	user, err := uc.userRepo.GetUserByID(c, userID, withDeleted)
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"filter": filter,
-			"limit":  limit,
-			"offset": offset,
-			"error":  err,
-		}).Error("Failed to get user information")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.FromContext(c).Error("Failed to get user",
+			"userID", userID,
+			"error", err,
+		)
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.Header("ETag", etag.Format(user.Version))
+	if etag.NotModified(c, user.Version) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, user)
 }
 
 func (uc *UserController) AddUser(c *gin.Context) {
 	var user models.User
-	if err := c.BindJSON(&user); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("Failed to bind model to data")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to bind model to data"})
+	if !bindJSON(c, &user) {
 		return
 	}
 
+	// A body carrying only the passport is completed from the People info API.
+	if user.Surname == "" && user.Name == "" && user.Address == "" && uc.peopleInfo.Enabled() {
+		passport := strings.Fields(user.PassportNumber)
+		people, err := uc.peopleInfo.Lookup(c, passport[0], passport[1])
+		if err != nil {
+			logger.FromContext(c).Error("Failed to get people info",
+				"error", err,
+			)
+			c.Error(err)
+			return
+		}
+		user.Surname, user.Name, user.Patronymic, user.Address = people.Surname, people.Name, people.Patronymic, people.Address
+		if err := validation.Struct(&user, language(c)); err != nil {
+			logger.FromContext(c).Error("People info returned invalid user data",
+				"user", user,
+				"error", err,
+			)
+			c.Error(&apperrors.ExternalAPIError{Message: "People info returned invalid user data"})
+			return
+		}
+	}
+
 	if err := uc.userRepo.CreateUser(c, &user); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"user":  user,
-			"error": err,
-		}).Error("An error occurred while trying to create a user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred while trying to create a user"})
+		logger.FromContext(c).Error("An error occurred while trying to create a user",
+			"user", user,
+			"error", err,
+		)
+		c.Error(err)
 		return
 	}
 
-	logger.Logger.WithFields(logrus.Fields{
-		"user": user,
-	}).Info("the user has been created and added!")
+	logger.FromContext(c).Info("the user has been created and added!",
+		"user", user,
+	)
 
 	c.JSON(http.StatusCreated, gin.H{"msg": "the user has been created and added!"})
 }
 
 func (uc *UserController) UpdateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		logger.FromContext(c).Error("Invalid user ID",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid user ID"})
+		return
+	}
+
 	var user models.User
-	if err := c.BindJSON(&user); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("Failed to bind model to data")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to bind model to data"})
+	if !bindJSON(c, &user) {
+		return
+	}
+	if user.ID != 0 && user.ID != userID {
+		c.Error(&apperrors.BadRequestError{Message: "User ID in the body does not match the path"})
+		return
+	}
+	user.ID = userID
+
+	cond, ok := precondition(c)
+	if !ok {
 		return
 	}
 
-	if err := uc.userRepo.UpdateUser(c, &user); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"user":  user,
-			"error": err,
-		}).Error("An error occurred while trying to update user information")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := uc.userRepo.UpdateUser(c, &user, cond); err != nil {
+		logger.FromContext(c).Error("An error occurred while trying to update user information",
+			"user", user,
+			"error", err,
+		)
+		c.Error(err)
 		return
 	}
 
-	logger.Logger.WithFields(logrus.Fields{
-		"user": user,
-	}).Info("User information has been successfully updated")
+	logger.FromContext(c).Info("User information has been successfully updated",
+		"user", user,
+	)
+	c.Header("ETag", etag.Format(user.Version))
 	c.JSON(http.StatusOK, gin.H{"msg": "User information has been successfully updated"})
 }
 
+func (uc *UserController) PatchUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		logger.FromContext(c).Error("Invalid user ID",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid user ID"})
+		return
+	}
+
+	if ct := c.ContentType(); ct != mergePatchContentType && ct != "application/json" {
+		c.Error(&apperrors.UnsupportedMediaTypeError{Message: "Content-Type must be " + mergePatchContentType})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.Error(&apperrors.BadRequestError{Message: "Failed to read request body"})
+		return
+	}
+	patch, err := parseUserPatch(body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	cond, ok := precondition(c)
+	if !ok {
+		return
+	}
+
+	lang := language(c)
+	user, err := uc.userRepo.PatchUser(c, userID, cond, func(user *models.User) error {
+		return patch.apply(user, lang)
+	})
+	if err != nil {
+		logger.FromContext(c).Error("An error occurred while trying to patch user information",
+			"userID", userID,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
+
+	logger.FromContext(c).Info("User information has been successfully patched",
+		"userID", userID,
+	)
+	c.Header("ETag", etag.Format(user.Version))
+	c.JSON(http.StatusOK, user)
+}
+
 func (uc *UserController) DeleteUser(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("userID"))
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID": c.Param("userID"),
-			"error":  err,
-		}).Error("Invalid user ID")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		logger.FromContext(c).Error("Invalid user ID",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid user ID"})
+		return
+	}
+	cond, ok := precondition(c)
+	if !ok {
 		return
 	}
-	if err := uc.userRepo.DeleteUser(c, userID); err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID": c.Param("userID"),
-			"error":  err,
-		}).Error("Failed to delete user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+	if err := uc.userRepo.DeleteUser(c, userID, cond); err != nil {
+		logger.FromContext(c).Error("Failed to delete user",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(err)
 		return
 	}
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": c.Param("userID"),
-		"error":  err,
-	}).Info("The user has been deleted")
+	logger.FromContext(c).Info("The user has been deleted",
+		"userID", c.Param("userID"),
+		"error", err,
+	)
 	c.JSON(http.StatusOK, gin.H{"msg": "The user has been deleted"})
 }
+
+func (uc *UserController) RestoreUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		logger.FromContext(c).Error("Invalid user ID",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid user ID"})
+		return
+	}
+
+	user, err := uc.userRepo.RestoreUser(c, userID)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to restore user",
+			"userID", userID,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
+
+	logger.FromContext(c).Info("The user has been restored",
+		"userID", userID,
+	)
+	c.Header("ETag", etag.Format(user.Version))
+	c.JSON(http.StatusOK, user)
+}
+
+func (uc *UserController) ExportUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		logger.FromContext(c).Error("Invalid user ID",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid user ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.Error(&apperrors.BadRequestError{Message: "Invalid export format"})
+		return
+	}
+
+	export, err := uc.userRepo.ExportUser(c, userID)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to export user data",
+			"userID", userID,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	archive, err := exportArchive(export)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to build export archive",
+			"userID", userID,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
+
+	logger.FromContext(c).Info("User data has been exported",
+		"userID", userID,
+	)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.zip"`, userID))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// exportArchive packs the profile and the time entries into separate documents of a ZIP file.
+func exportArchive(export *models.UserExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"tasks.json", export.Tasks},
+	}
+	for _, file := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (uc *UserController) EraseUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		logger.FromContext(c).Error("Invalid user ID",
+			"userID", c.Param("userID"),
+			"error", err,
+		)
+		c.Error(&apperrors.BadRequestError{Message: "Invalid user ID"})
+		return
+	}
+
+	var req models.EraseRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	// The route requires an admin token, so the caller is always known.
+	principal, _ := auth.FromContext(c.Request.Context())
+	req.RequestedBy = principal.Subject
+
+	record, err := uc.userRepo.EraseUser(c, userID, &req)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to erase user data",
+			"userID", userID,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
+
+	logger.FromContext(c).Info("User personal data has been erased",
+		"userID", userID,
+		"requestedBy", record.RequestedBy,
+	)
+	c.JSON(http.StatusOK, record)
+}
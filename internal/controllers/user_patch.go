@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+	"time-tracker/internal/validation"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// userPatch is a JSON Merge Patch (RFC 7396) document for a user. Members
+// set to null are removed, absent members are left untouched.
+type userPatch map[string]json.RawMessage
+
+func parseUserPatch(body []byte) (userPatch, error) {
+	var patch userPatch
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return nil, &apperrors.BadRequestError{Message: "Merge patch must be a JSON object"}
+	}
+	return patch, nil
+}
+
+// apply merges the patch into user and validates the result.
+func (p userPatch) apply(user *models.User, lang validation.Lang) error {
+	editable := map[string]*string{
+		"passportNumber": &user.PassportNumber,
+		"surname":        &user.Surname,
+		"name":           &user.Name,
+		"patronymic":     &user.Patronymic,
+		"address":        &user.Address,
+	}
+
+	fields := make(map[string]string)
+	for key, raw := range p {
+		dst, ok := editable[key]
+		if !ok {
+			fields[key] = validation.Message(lang, "unknownField")
+			continue
+		}
+		if string(raw) == "null" {
+			*dst = ""
+			continue
+		}
+		if err := json.Unmarshal(raw, dst); err != nil {
+			fields[key] = validation.Message(lang, "string")
+		}
+	}
+	if len(fields) > 0 {
+		return &apperrors.ValidationError{Message: validation.Message(lang, "invalid"), Fields: fields}
+	}
+
+	err := validation.Struct(user, lang)
+	var invalid *apperrors.ValidationError
+	if errors.As(err, &invalid) {
+		// Rows created before the passport format was enforced stay
+		// editable as long as the patch leaves the passport alone.
+		if _, touched := p["passportNumber"]; !touched {
+			delete(invalid.Fields, "passportNumber")
+		}
+		if len(invalid.Fields) == 0 {
+			return nil
+		}
+	}
+	return err
+}
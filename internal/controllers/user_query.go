@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// userQueryParams are the query parameters of GET /api/users that are not field filters.
+var userQueryParams = map[string]bool{
+	"limit":          true,
+	"cursor":         true,
+	"sort":           true,
+	"includeDeleted": true,
+}
+
+// parseUserQuery reads filters written as "field=value" or "field[op]=value",
+// "sort=surname,-createdAt", "limit" and "cursor". Values of the "in"
+// operator are comma separated.
+func parseUserQuery(values url.Values) (models.UserQuery, error) {
+	query := models.UserQuery{
+		Limit:  defaultUserPageSize,
+		Cursor: values.Get("cursor"),
+	}
+
+	// Offset paging was replaced by cursors; say so rather than treating
+	// "offset" as an unknown field.
+	if values.Has("offset") {
+		return query, &apperrors.BadRequestError{Message: `The "offset" parameter is not supported, use "cursor" with the "next" value of the previous page`}
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return query, &apperrors.BadRequestError{Message: "Invalid limit value"}
+		}
+		if limit > maxUserPageSize {
+			limit = maxUserPageSize
+		}
+		query.Limit = limit
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		sort, ok := models.ParseSort(raw)
+		if !ok {
+			return query, &apperrors.BadRequestError{Message: "Invalid sort value"}
+		}
+		query.Sort = sort
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if !userQueryParams[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, op := key, models.OpEq
+		if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+			field, op = key[:open], models.FilterOp(key[open+1:len(key)-1])
+		}
+		for _, raw := range values[key] {
+			filter := models.FieldFilter{Field: field, Op: op, Values: []string{raw}}
+			if op == models.OpIn {
+				filter.Values = strings.Split(raw, ",")
+			}
+			query.Filters = append(query.Filters, filter)
+		}
+	}
+
+	return query, nil
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"time-tracker/internal/apperrors"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+type WebhookController struct {
+	webhookRepo *db.WebhookRepository
+}
+
+func NewWebhookController(webhookRepo *db.WebhookRepository) *WebhookController {
+	return &WebhookController{webhookRepo: webhookRepo}
+}
+
+// @Summary     List webhook subscriptions
+// @Tags        webhooks
+// @Produce     json
+// @Success     200 {array}  models.WebhookSubscription
+// @Failure     403 {object} problem.Problem
+// @Failure     500 {object} problem.Problem
+// @Router      /webhooks [get]
+func (wc *WebhookController) GetSubscriptions(c *gin.Context) {
+	subs, err := wc.webhookRepo.GetSubscriptions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+// @Summary     Subscribe to events
+// @Description Register a URL that receives task.started, task.ended, user.created, user.updated and user.deleted events.
+// @Description Requests are signed with HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>" using the secret, sent as X-Webhook-Signature: sha256=<hex>.
+// @Tags        webhooks
+// @Accept      json
+// @Produce     json
+// @Param       subscription body     models.WebhookRequest true "Subscription"
+// @Success     201          {object} models.WebhookSubscription
+// @Failure     400          {object} problem.Problem
+// @Failure     403          {object} problem.Problem
+// @Failure     422          {object} problem.Problem
+// @Failure     500          {object} problem.Problem
+// @Router      /webhooks [post]
+func (wc *WebhookController) CreateSubscription(c *gin.Context) {
+	var req models.WebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sub, err := wc.webhookRepo.CreateSubscription(c, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
+
+// @Summary     Delete a webhook subscription
+// @Tags        webhooks
+// @Produce     json
+// @Param       webhookID path     int true "Subscription ID"
+// @Success     204
+// @Failure     400       {object} problem.Problem
+// @Failure     403       {object} problem.Problem
+// @Failure     404       {object} problem.Problem
+// @Failure     500       {object} problem.Problem
+// @Router      /webhooks/{webhookID} [delete]
+func (wc *WebhookController) DeleteSubscription(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil {
+		c.Error(&apperrors.BadRequestError{Message: "Invalid webhook ID"})
+		return
+	}
+
+	if err := wc.webhookRepo.DeleteSubscription(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary     Webhook delivery log
+// @Description List the latest deliveries of a subscription, newest first
+// @Tags        webhooks
+// @Produce     json
+// @Param       webhookID path     int true  "Subscription ID"
+// @Param       limit     query    int false "Number of deliveries"
+// @Success     200       {array}  models.WebhookDelivery
+// @Failure     400       {object} problem.Problem
+// @Failure     403       {object} problem.Problem
+// @Failure     404       {object} problem.Problem
+// @Failure     500       {object} problem.Problem
+// @Router      /webhooks/{webhookID}/deliveries [get]
+func (wc *WebhookController) GetDeliveries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("webhookID"))
+	if err != nil {
+		c.Error(&apperrors.BadRequestError{Message: "Invalid webhook ID"})
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.Error(&apperrors.BadRequestError{Message: "Invalid limit value"})
+			return
+		}
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+
+	deliveries, err := wc.webhookRepo.GetDeliveries(c, id, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary     Redeliver a webhook
+// @Description Queue the event of a past delivery again. The new delivery is sent with the same X-Webhook-ID.
+// @Tags        webhooks
+// @Produce     json
+// @Param       deliveryID path     int true "Delivery ID"
+// @Success     202        {object} models.WebhookDelivery
+// @Failure     400        {object} problem.Problem
+// @Failure     403        {object} problem.Problem
+// @Failure     404        {object} problem.Problem
+// @Failure     500        {object} problem.Problem
+// @Router      /webhooks/deliveries/{deliveryID}/redeliver [post]
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("deliveryID"), 10, 64)
+	if err != nil {
+		c.Error(&apperrors.BadRequestError{Message: "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := wc.webhookRepo.Redeliver(c, id)
+	if err != nil {
+		logger.FromContext(c).Error("Failed to redeliver webhook",
+			"deliveryID", id,
+			"error", err,
+		)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"time-tracker/internal/auth"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/requestid"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionErase   = "erase"
+
+	AuditEntityUser = "user"
+	AuditEntityTask = "task"
+
+	anonymousActor = "anonymous"
+)
+
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) GetEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	logger.FromContext(ctx).Debug("Getting audit events",
+		"filter", filter,
+	)
+
+	var argID int = 1
+	query := "SELECT id, actor, action, entity_type, entity_id, before, after, diff, request_id, created_at FROM audit_events WHERE true"
+	args := []interface{}{}
+
+	conditions := []struct {
+		column string
+		op     string
+		value  interface{}
+		set    bool
+	}{
+		{"entity_type", "=", filter.EntityType, filter.EntityType != ""},
+		{"entity_id", "=", filter.EntityID, filter.EntityID != 0},
+		{"actor", "=", filter.Actor, filter.Actor != ""},
+		{"action", "=", filter.Action, filter.Action != ""},
+		{"created_at", ">=", filter.From, !filter.From.IsZero()},
+		{"created_at", "<", filter.To, !filter.To.IsZero()},
+	}
+	for _, cond := range conditions {
+		if !cond.set {
+			continue
+		}
+		query += fmt.Sprintf(" AND %s %s $%d", cond.column, cond.op, argID)
+		args = append(args, cond.value)
+		argID++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", argID, argID+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while retrieving audit events",
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var before, after, diff []byte
+		if err := rows.Scan(&event.ID, &event.Actor, &event.Action, &event.EntityType, &event.EntityID, &before, &after, &diff, &event.RequestID, &event.CreatedAt); err != nil {
+			logger.FromContext(ctx).Error("An error occurred while scanning audit events",
+				"error", err,
+			)
+			return nil, err
+		}
+		event.Before, event.After, event.Diff = before, after, diff
+		events = append(events, event)
+	}
+	if rows.Err() != nil {
+		logger.FromContext(ctx).Error("An error occurred when trying to iterate over audit events",
+			"error", rows.Err(),
+		)
+		return nil, rows.Err()
+	}
+
+	logger.FromContext(ctx).Info("Audit events successfully received",
+		"count", len(events),
+	)
+
+	return events, nil
+}
+
+// recordAudit stores an audit event inside the caller's transaction so that
+// it is committed or rolled back together with the change it describes.
+// The actor and request ID are taken from ctx.
+func recordAudit(ctx context.Context, tx pgx.Tx, action, entityType string, entityID int, before, after interface{}) error {
+	beforeJSON, beforeMap, err := auditSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, afterMap, err := auditSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	var diffJSON []byte
+	if diff := auditDiff(beforeMap, afterMap); len(diff) > 0 {
+		if diffJSON, err = json.Marshal(diff); err != nil {
+			return err
+		}
+	}
+
+	actor := anonymousActor
+	if principal, ok := auth.FromContext(ctx); ok {
+		actor = principal.Subject
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audit_events (actor, action, entity_type, entity_id, before, after, diff, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+	`, actor, action, entityType, entityID, beforeJSON, afterJSON, diffJSON, requestid.FromContext(ctx))
+	return err
+}
+
+func auditSnapshot(entity interface{}) ([]byte, map[string]interface{}, error) {
+	if v := reflect.ValueOf(entity); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, nil, nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+	return data, fields, nil
+}
+
+// auditDiff lists every field whose value differs between the two snapshots.
+func auditDiff(before, after map[string]interface{}) map[string]map[string]interface{} {
+	diff := make(map[string]map[string]interface{})
+	for key, old := range before {
+		if val, ok := after[key]; !ok || !reflect.DeepEqual(old, val) {
+			diff[key] = map[string]interface{}{"from": old, "to": after[key]}
+		}
+	}
+	for key, val := range after {
+		if _, ok := before[key]; !ok {
+			diff[key] = map[string]interface{}{"from": nil, "to": val}
+		}
+	}
+	return diff
+}
+
+// scrubAudit removes personal data from the audit trail of an erased user.
+func scrubAudit(ctx context.Context, tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE audit_events
+		SET before = NULL, after = NULL, diff = NULL
+		WHERE (entity_type = $1 AND entity_id = $3)
+		   OR (entity_type = $2 AND (before->>'userId' = $4 OR after->>'userId' = $4))
+	`, AuditEntityUser, AuditEntityTask, userID, strconv.Itoa(userID))
+	return err
+}
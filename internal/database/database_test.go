@@ -0,0 +1,11 @@
+package database_test
+
+import (
+	"testing"
+
+	"time-tracker/internal/storage/storagetest"
+)
+
+// TestConformance runs against the database named by
+// STORAGETEST_DATABASE_URL and is skipped without it.
+func TestConformance(t *testing.T) { storagetest.Run(t, storagetest.Postgres) }
@@ -3,34 +3,38 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
 	"time"
 
+	"time-tracker/internal/config"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/tracing"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var Pool *pgxpool.Pool
 
-func ConnectDatabase() *pgxpool.Pool {
-	host := os.Getenv("POSTGRES_HOST")
-	port := os.Getenv("POSTGRES_PORT")
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_NAME")
-
-	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", user, password, host, port, dbname)
+// ConnectDatabase creates the pool. An unreachable database is only logged:
+// the pool reconnects lazily and /readyz reports the outage meanwhile.
+func ConnectDatabase(cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid database config: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = &tracing.PgxTracer{DatabaseName: cfg.Name}
 
-	dbpool, err := pgxpool.New(context.Background(), connString)
+	dbpool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		log.Fatalf("error connect with db: %v\n", err)
+		return nil, fmt.Errorf("error connect with db: %w", err)
 	}
 
-	context, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
-	if err := dbpool.Ping(context); err != nil {
-		log.Fatalf("False oing request: %v\n", err)
+	if err := dbpool.Ping(ctx); err != nil {
+		logger.Logger.Warn("Database is not reachable yet",
+			"error", err,
+		)
 	}
 
-	return dbpool
+	return dbpool, nil
 }
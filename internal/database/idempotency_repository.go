@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdempotencyRepository stores the responses of requests sent with an
+// Idempotency-Key so that retries can be answered without running them again.
+type IdempotencyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(db *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims key within scope for a request with the given hash. A nil
+// response means the caller holds the reservation and must Complete or
+// Release it. Otherwise the response recorded for the same request is
+// returned. Expired keys are reused; a reservation older than lockTimeout is
+// considered abandoned and handed over to a retry of the same request.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, scope, key, hash string, ttl, lockTimeout time.Duration) (*models.StoredResponse, error) {
+	// The loop covers a reservation being released between the insert and
+	// the select.
+	for attempt := 0; attempt < 3; attempt++ {
+		now := time.Now()
+		var reserved bool
+		err := r.db.QueryRow(ctx, `
+			INSERT INTO idempotency_keys (scope, key, request_hash, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (scope, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash,
+			    status_code = NULL,
+			    response_headers = NULL,
+			    response_body = NULL,
+			    created_at = EXCLUDED.created_at,
+			    expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= $4
+			   OR (idempotency_keys.status_code IS NULL
+			       AND idempotency_keys.created_at <= $6
+			       AND idempotency_keys.request_hash = EXCLUDED.request_hash)
+			RETURNING true
+		`, scope, key, hash, now, now.Add(ttl), now.Add(-lockTimeout)).Scan(&reserved)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.FromContext(ctx).Error("An error occurred while reserving an idempotency key",
+				"key", key,
+				"error", err,
+			)
+			return nil, err
+		}
+
+		var (
+			storedHash string
+			status     *int
+			header     map[string]string
+			body       []byte
+		)
+		err = r.db.QueryRow(ctx, `
+			SELECT request_hash, status_code, response_headers, response_body
+			FROM idempotency_keys
+			WHERE scope = $1 AND key = $2
+		`, scope, key).Scan(&storedHash, &status, &header, &body)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("An error occurred while reading an idempotency key",
+				"key", key,
+				"error", err,
+			)
+			return nil, err
+		}
+
+		if storedHash != hash {
+			return nil, &apperrors.IdempotencyKeyReusedError{Message: "Idempotency-Key has already been used for a different request"}
+		}
+		if status == nil {
+			break
+		}
+		return &models.StoredResponse{Status: *status, Header: header, Body: body}, nil
+	}
+	return nil, &apperrors.RequestInProgressError{Message: "A request with this Idempotency-Key is still being processed"}
+}
+
+// Complete records the response of a reserved key.
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, resp *models.StoredResponse) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, response_headers = $4, response_body = $5
+		WHERE scope = $1 AND key = $2
+	`, scope, key, resp.Status, header, resp.Body)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while storing an idempotent response",
+			"key", key,
+			"error", err,
+		)
+	}
+	return err
+}
+
+// Release drops a reservation without a response so that the request can be
+// retried.
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND status_code IS NULL
+	`, scope, key)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while releasing an idempotency key",
+			"key", key,
+			"error", err,
+		)
+	}
+	return err
+}
+
+// PurgeExpired deletes the keys that expired before the given moment.
+func (r *IdempotencyRepository) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while purging expired idempotency keys",
+			"error", err,
+		)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// scrubIdempotency blanks the task descriptions in the responses recorded for
+// the tasks of an erased user. The keys are kept, so retries are still
+// answered from the store rather than run again.
+func scrubIdempotency(ctx context.Context, tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET response_body = convert_to((convert_from(response_body, 'UTF8')::jsonb || '{"description": ""}')::text, 'UTF8')
+		WHERE CASE WHEN response_headers->>'Content-Type' LIKE 'application/json%'
+		           THEN convert_from(response_body, 'UTF8')::jsonb->>'userId'
+		      END = $1
+	`, strconv.Itoa(userID))
+	return err
+}
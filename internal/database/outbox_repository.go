@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// outboxRelayLock is the advisory lock key held by the active relay.
+const outboxRelayLock = 7_246_375_002
+
+// EventsChannel is the LISTEN/NOTIFY channel every domain event is announced
+// on when its transaction commits. The payload is the JSON encoded Event.
+const EventsChannel = "events"
+
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Relay hands up to limit pending events to publish in outbox order and
+// marks the published ones. It stops at the first event publish fails on,
+// recording and returning the error, so that later events never overtake it;
+// the event is retried on the next call.
+//
+// Only one relay works at a time across all instances: it holds an advisory
+// lock for the duration of the transaction, and returns immediately when
+// another relay holds it. Rows are selected with SKIP LOCKED so that the
+// relay never waits behind a transaction that has locked pending rows.
+func (r *OutboxRepository) Relay(ctx context.Context, limit int, publish func(event models.OutboxEvent) error) (published int, err error) {
+	var failed error
+	err = pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var locked bool
+		if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLock).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT id, event_id, event_type, aggregate_type, aggregate_id, payload, created_at, attempts
+			FROM outbox
+			WHERE published_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return err
+		}
+		events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OutboxEvent, error) {
+			var e models.OutboxEvent
+			err := row.Scan(&e.ID, &e.EventID, &e.Type, &e.AggregateType, &e.AggregateID, &e.Payload, &e.CreatedAt, &e.Attempts)
+			return e, err
+		})
+		if err != nil {
+			return err
+		}
+
+		var ids []int64
+		for _, event := range events {
+			if publishErr := publish(event); publishErr != nil {
+				failed = fmt.Errorf("publish event %s: %w", event.EventID, publishErr)
+				if _, err := tx.Exec(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`, event.ID, publishErr.Error()); err != nil {
+					return err
+				}
+				break
+			}
+			ids = append(ids, event.ID)
+		}
+
+		if len(ids) > 0 {
+			if _, err := tx.Exec(ctx, `UPDATE outbox SET published_at = now(), last_error = '' WHERE id = ANY ($1)`, ids); err != nil {
+				return err
+			}
+		}
+		published = len(ids)
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while relaying outbox events",
+			"error", err,
+		)
+		return 0, err
+	}
+	// The failure is committed together with the progress made before it.
+	return published, failed
+}
+
+// Pending returns the number of events waiting to be published.
+func (r *OutboxRepository) Pending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM outbox WHERE published_at IS NULL`).Scan(&count)
+	return count, err
+}
+
+// PurgePublished deletes the events published before the given moment.
+func (r *OutboxRepository) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM outbox WHERE published_at < $1`, before)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while purging the outbox",
+			"error", err,
+		)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// recordEvent writes a domain event to the outbox inside the caller's
+// transaction, so that it is published if and only if the change it
+// describes is committed. The event is also announced on EventsChannel;
+// NOTIFY is transactional as well, so listeners on every instance receive it
+// right after the commit without waiting for the relay.
+func recordEvent(ctx context.Context, tx pgx.Tx, eventType, aggregateType string, aggregateID int, data interface{}) error {
+	event := models.Event{ID: newEventID(), Type: eventType, OccurredAt: time.Now().UTC(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox (event_id, event_type, aggregate_type, aggregate_id, payload)
+		VALUES ($1, $2, $3, $4, $5)
+	`, event.ID, eventType, aggregateType, aggregateID, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `SELECT pg_notify($1, $2)`, EventsChannel, string(payload))
+	return err
+}
+
+// scrubOutbox removes personal data from the pending and published events of
+// an erased user, so that the relay only publishes the erased data.
+func scrubOutbox(ctx context.Context, tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(ctx, `UPDATE outbox SET payload = `+scrubbedEventPayload+` WHERE `+eventOfUser, strconv.Itoa(userID))
+	return err
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"time-tracker/internal/logger"
+	"time-tracker/internal/ratelimit"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateLimitRepository is a ratelimit.Store shared by all instances.
+type RateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Take advances the TAT of key in a single statement; the conditional
+// update leaves the row alone when the request is over the limit.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	emission := limit.Emission().Seconds()
+	tolerance := limit.Tolerance().Seconds()
+
+	var tat time.Time
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO rate_limits (key, tat)
+		VALUES ($1, $2::timestamptz + make_interval(secs => $3))
+		ON CONFLICT (key) DO UPDATE
+		SET tat = GREATEST(rate_limits.tat, $2) + make_interval(secs => $3)
+		WHERE GREATEST(rate_limits.tat, $2) + make_interval(secs => $3) <= $2::timestamptz + make_interval(secs => $4)
+		RETURNING tat
+	`, key, now, emission, tolerance).Scan(&tat)
+	if err == nil {
+		return limit.Result(tat, now, true), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return ratelimit.Result{}, err
+	}
+
+	if err := r.db.QueryRow(ctx, `SELECT tat FROM rate_limits WHERE key = $1`, key).Scan(&tat); err != nil {
+		return ratelimit.Result{}, err
+	}
+	return limit.Result(tat, now, false), nil
+}
+
+// PurgeExpired deletes the buckets that were full before the given moment.
+func (r *RateLimitRepository) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM rate_limits WHERE tat <= $1`, before)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while purging rate limit buckets",
+			"error", err,
+		)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
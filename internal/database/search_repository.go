@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"strings"
+
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	startSel        = "<mark>"
+	stopSel         = "</mark>"
+	headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", HighlightAll=true"
+)
+
+// headlines returns the SQL expressions of the headlines of the text
+// expression under the Russian and the English configuration. The text is
+// HTML-escaped first, so the marks are the only markup in a headline; the
+// headlines are combined with mergeHighlights.
+func headlines(text string) string {
+	escaped := `replace(replace(replace(replace(replace(` + text + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+	return `ts_headline('russian', ` + escaped + `, q.query, '` + headlineOptions + `'),
+		ts_headline('english', ` + escaped + `, q.query, '` + headlineOptions + `')`
+}
+
+// mergeHighlights marks every part of a text that is marked in any of the
+// headline versions of it.
+func mergeHighlights(versions ...string) string {
+	var text []byte
+	var marked []bool
+	for i, headline := range versions {
+		plain, marks := unmark(headline)
+		if i == 0 {
+			text, marked = plain, marks
+			continue
+		}
+		if string(plain) != string(text) {
+			continue
+		}
+		for j := range marks {
+			marked[j] = marked[j] || marks[j]
+		}
+	}
+
+	var b strings.Builder
+	open := false
+	for i, c := range text {
+		if marked[i] != open {
+			if open {
+				b.WriteString(stopSel)
+			} else {
+				b.WriteString(startSel)
+			}
+			open = marked[i]
+		}
+		b.WriteByte(c)
+	}
+	if open {
+		b.WriteString(stopSel)
+	}
+	return b.String()
+}
+
+// unmark strips the marks from a headline and reports which bytes of the
+// text were marked.
+func unmark(headline string) ([]byte, []bool) {
+	text := make([]byte, 0, len(headline))
+	marked := make([]bool, 0, len(headline))
+	open := false
+	for i := 0; i < len(headline); {
+		switch {
+		case strings.HasPrefix(headline[i:], startSel):
+			open = true
+			i += len(startSel)
+		case strings.HasPrefix(headline[i:], stopSel):
+			open = false
+			i += len(stopSel)
+		default:
+			text = append(text, headline[i])
+			marked = append(marked, open)
+			i++
+		}
+	}
+	return text, marked
+}
+
+// The seed data is Russian, so queries are parsed with both the Russian and
+// the English configuration and the resulting tsqueries are OR-ed.
+const searchQueryCTE = `
+	WITH q AS (
+		SELECT websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS query
+	)`
+
+type SearchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSearchRepository(db *pgxpool.Pool) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+func (r *SearchRepository) SearchUsers(ctx context.Context, text string, limit int) ([]models.UserSearchHit, error) {
+	logger.FromContext(ctx).Debug("Searching users",
+		"query", text,
+		"limit", limit,
+	)
+
+	// Full-text matches are combined with trigram word similarity on the full
+	// name so that misspelt names are still found.
+	query := searchQueryCTE + `
+	SELECT u.id, coalesce(u.surname, ''), coalesce(u.name, ''), coalesce(u.patronymic, ''),
+		GREATEST(ts_rank(u.search_vector, q.query), word_similarity($1, u.full_name))::float8 AS rank,
+		` + headlines("u.full_name") + `,
+		` + headlines("coalesce(u.address, '')") + `
+	FROM users u, q
+	WHERE u.deleted_at IS NULL
+	  AND (u.search_vector @@ q.query OR $1 <% u.full_name)
+	ORDER BY rank DESC, u.id
+	LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, text, limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while searching users",
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []models.UserSearchHit{}
+	for rows.Next() {
+		var hit models.UserSearchHit
+		var name, address [2]string
+		if err := rows.Scan(&hit.ID, &hit.Surname, &hit.Name, &hit.Patronymic, &hit.Rank, &name[0], &name[1], &address[0], &address[1]); err != nil {
+			logger.FromContext(ctx).Error("An error occurred while scanning user search hits",
+				"error", err,
+			)
+			return nil, err
+		}
+		hit.NameHighlight = mergeHighlights(name[:]...)
+		// The address is only shown when it matches.
+		if highlight := mergeHighlights(address[:]...); strings.Contains(highlight, startSel) {
+			hit.AddressHighlight = highlight
+		}
+		hits = append(hits, hit)
+	}
+	if rows.Err() != nil {
+		logger.FromContext(ctx).Error("An error occurred when trying to iterate over user search hits",
+			"error", rows.Err(),
+		)
+		return nil, rows.Err()
+	}
+
+	logger.FromContext(ctx).Info("User search completed",
+		"query", text,
+		"count", len(hits),
+	)
+
+	return hits, nil
+}
+
+func (r *SearchRepository) SearchTasks(ctx context.Context, text string, limit int) ([]models.TaskSearchHit, error) {
+	logger.FromContext(ctx).Debug("Searching tasks",
+		"query", text,
+		"limit", limit,
+	)
+
+	query := searchQueryCTE + `
+	SELECT t.id, t.user_id, t.description,
+		ts_rank(t.search_vector, q.query)::float8 AS rank,
+		` + headlines("t.description") + `
+	FROM tasks t, q
+	WHERE t.deleted_at IS NULL AND t.search_vector @@ q.query
+	ORDER BY rank DESC, t.id
+	LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, text, limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while searching tasks",
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []models.TaskSearchHit{}
+	for rows.Next() {
+		var hit models.TaskSearchHit
+		var description [2]string
+		if err := rows.Scan(&hit.ID, &hit.UserID, &hit.Description, &hit.Rank, &description[0], &description[1]); err != nil {
+			logger.FromContext(ctx).Error("An error occurred while scanning task search hits",
+				"error", err,
+			)
+			return nil, err
+		}
+		hit.Highlight = mergeHighlights(description[:]...)
+		hits = append(hits, hit)
+	}
+	if rows.Err() != nil {
+		logger.FromContext(ctx).Error("An error occurred when trying to iterate over task search hits",
+			"error", rows.Err(),
+		)
+		return nil, rows.Err()
+	}
+
+	logger.FromContext(ctx).Info("Task search completed",
+		"query", text,
+		"count", len(hits),
+	)
+
+	return hits, nil
+}
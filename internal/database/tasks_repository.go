@@ -2,15 +2,22 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
+	"time-tracker/internal/apperrors"
 	"time-tracker/internal/logger"
+	"time-tracker/internal/metrics"
 	"time-tracker/internal/models"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/sirupsen/logrus"
 )
 
+const taskColumns = "id, user_id, description, start_time, end_time, created_at, updated_at, deleted_at, version"
+
 type TaskRepository struct {
 	db *pgxpool.Pool
 }
@@ -19,106 +26,322 @@ func NewTaskRepository(db *pgxpool.Pool) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
-func (r *TaskRepository) GetUserTasksByPeriod(ctx context.Context, userID int, start, end time.Time) ([]models.Task, error) {
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": userID,
-		"start":  start,
-		"end":    end,
-	}).Debug("Request for user tasks for a period")
+func scanTask(row pgx.Row, task *models.Task) error {
+	return row.Scan(&task.ID, &task.UserID, &task.Description, &task.StartTime, &task.EndTime, &task.CreatedAt, &task.UpdatedAt, &task.DeletedAt, &task.Version)
+}
+
+func (r *TaskRepository) GetUserTasksByPeriod(ctx context.Context, userID int, start, end time.Time, includeDeleted bool) ([]models.Task, error) {
+	logger.FromContext(ctx).Debug("Request for user tasks for a period",
+		"userID", userID,
+		"start", start,
+		"end", end,
+		"includeDeleted", includeDeleted,
+	)
 
 	var tasks []models.Task
 	query := `
-	SELECT id, user_id, description, start_time, end_time, created_at, updated_at
+	SELECT ` + taskColumns + `
 	FROM tasks
-	WHERE user_id = $1 AND start_time >= $2 AND end_time <= $3
+	WHERE user_id = $1 AND start_time >= $2 AND end_time <= $3 AND ($4 OR deleted_at IS NULL)
 	ORDER BY EXTRACT(EPOCH FROM (end_time - start_time)) DESC
 	`
-	rows, err := r.db.Query(ctx, query, userID, start, end)
+	rows, err := r.db.Query(ctx, query, userID, start, end, includeDeleted)
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("An error occurred while executing a request to receive tasks")
+		logger.FromContext(ctx).Error("An error occurred while executing a request to receive tasks",
+			"error", err,
+		)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var task models.Task
-		if err := rows.Scan(&task.ID, &task.UserID, &task.Description, &task.StartTime, &task.EndTime, &task.CreatedAt, &task.UpdatedAt); err != nil {
-			logger.Logger.WithFields(logrus.Fields{
-				"error": err,
-			}).Error("An error occurred while scanning the task line")
+		if err := scanTask(rows, &task); err != nil {
+			logger.FromContext(ctx).Error("An error occurred while scanning the task line",
+				"error", err,
+			)
 			return nil, err
 		}
 		tasks = append(tasks, task)
 	}
 	if rows.Err() != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("An error occurred while iterating through the data rows")
+		logger.FromContext(ctx).Error("An error occurred while iterating through the data rows",
+			"error", err,
+		)
 		return nil, rows.Err()
 	}
 
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": userID,
-		"start":  start,
-		"end":    end,
-		"count":  len(tasks),
-	}).Info("Successfully received user tasks for the period")
+	logger.FromContext(ctx).Info("Successfully received user tasks for the period",
+		"userID", userID,
+		"start", start,
+		"end", end,
+		"count", len(tasks),
+	)
 
 	return tasks, nil
 }
 
-func (r *TaskRepository) StartTask(ctx context.Context, userID int, description string) error {
-	logger.Logger.WithFields(logrus.Fields{
-		"userID":      userID,
-		"description": description,
-	}).Debug("Начало новой таски")
+func (r *TaskRepository) StartTask(ctx context.Context, userID int, description string) (*models.Task, error) {
+	logger.FromContext(ctx).Debug("Начало новой таски",
+		"userID", userID,
+		"description", description,
+	)
 
-	query := `
+	task := &models.Task{}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
 			INSERT INTO tasks (user_id, description, start_time, created_at, updated_at)
-			VALUES ($1, $2, NOW(), NOW(), NOW())
-		`
-	_, err := r.db.Exec(ctx, query, userID, description)
+			SELECT $1, $2, NOW(), NOW(), NOW()
+			WHERE EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)
+			RETURNING ` + taskColumns
+		err := scanTask(tx.QueryRow(ctx, query, userID, description), task)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &apperrors.NoUserError{Message: fmt.Sprintf("User with id %v doesn't exist", userID)}
+		}
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditActionCreate, AuditEntityTask, task.ID, nil, task); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, models.EventTaskStarted, AuditEntityTask, task.ID, task)
+	})
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID":      userID,
-			"description": description,
-			"error":       err,
-		}).Error("An error occurred when trying to start a new task")
-		return err
+		logger.FromContext(ctx).Error("An error occurred when trying to start a new task",
+			"userID", userID,
+			"description", description,
+			"error", err,
+		)
+		return nil, err
 	}
+	metrics.TaskStarted()
 
-	logger.Logger.WithFields(logrus.Fields{
-		"userID":      userID,
-		"description": description,
-	}).Info("Таска успешно начата")
+	logger.FromContext(ctx).Info("Таска успешно начата",
+		"userID", userID,
+		"description", description,
+		"taskID", task.ID,
+	)
 
-	return nil
+	return task, nil
 }
 
-func (r *TaskRepository) EndTask(ctx context.Context, taskID int) error {
-	logger.Logger.WithFields(logrus.Fields{
-		"taskID": taskID,
-	}).Debug("End of task")
+func (r *TaskRepository) GetTaskByID(ctx context.Context, id int, includeDeleted bool) (*models.Task, error) {
+	logger.FromContext(ctx).Debug("Getting a task by ID",
+		"taskID", id,
+		"includeDeleted", includeDeleted,
+	)
 
-	query := `
+	task := &models.Task{}
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id=$1`
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	err := scanTask(r.db.QueryRow(ctx, query, id), task)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &apperrors.NoTaskError{Message: fmt.Sprintf("No task with id %v", id)}
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while retrieving the task",
+			"taskID", id,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Task successfully received",
+		"taskID", id,
+	)
+
+	return task, nil
+}
+
+func (r *TaskRepository) EndTask(ctx context.Context, taskID int, cond models.Precondition) (*models.Task, error) {
+	logger.FromContext(ctx).Debug("End of task",
+		"taskID", taskID,
+	)
+
+	task := &models.Task{}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before := &models.Task{}
+		err := scanTask(tx.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, taskID), before)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &apperrors.NoTaskError{Message: fmt.Sprintf("No task with id %v", taskID)}
+		}
+		if err != nil {
+			return err
+		}
+		if !cond.Matches(before.Version) {
+			return &apperrors.PreconditionFailedError{Message: fmt.Sprintf("Task with id %v has been modified, current version is %v", taskID, before.Version)}
+		}
+		if before.EndTime != nil {
+			return &apperrors.TaskAlreadyEndedError{Message: "Task already ended"}
+		}
+
+		query := `
 			UPDATE tasks
-			SET end_time = NOW(), updated_at = NOW()
-			WHERE id = $1 AND end_time IS NULL
-		`
-	_, err := r.db.Exec(ctx, query, taskID)
+			SET end_time = NOW(), updated_at = NOW(), version = version + 1
+			WHERE id = $1
+			RETURNING ` + taskColumns
+		if err := scanTask(tx.QueryRow(ctx, query, taskID), task); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditActionUpdate, AuditEntityTask, taskID, before, task); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, models.EventTaskEnded, AuditEntityTask, taskID, task)
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while completing the task",
+			"taskID", taskID,
+			"error", err,
+		)
+		return nil, err
+	}
+	metrics.TaskEnded()
+
+	logger.FromContext(ctx).Info("Task completed successfully",
+		"taskID", taskID,
+	)
+
+	return task, nil
+}
+
+// CountRunningTasks returns the number of started tasks that have not ended yet.
+func (r *TaskRepository) CountRunningTasks(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE end_time IS NULL AND deleted_at IS NULL`).Scan(&count)
+	return count, err
+}
+
+// GetRunningTasks returns the started tasks that have not ended yet, of the
+// given users or of every user when userIDs is empty.
+func (r *TaskRepository) GetRunningTasks(ctx context.Context, userIDs []int) ([]models.Task, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE end_time IS NULL AND deleted_at IS NULL
+		  AND (coalesce(cardinality($1::int[]), 0) = 0 OR user_id = ANY ($1))
+		ORDER BY start_time, id
+	`, userIDs)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while retrieving running tasks",
+			"userIDs", userIDs,
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// GetTasksByPeriod is GetUserTasksByPeriod for several users at once,
+// leaving out deleted tasks. Tasks are ordered by user, then by duration,
+// longest first.
+func (r *TaskRepository) GetTasksByPeriod(ctx context.Context, userIDs []int, start, end time.Time) ([]models.Task, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE user_id = ANY ($1) AND start_time >= $2 AND end_time <= $3 AND deleted_at IS NULL
+		ORDER BY user_id, EXTRACT(EPOCH FROM (end_time - start_time)) DESC
+	`, userIDs, start, end)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while retrieving tasks for a period",
+			"userIDs", userIDs,
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// GetTotals sums up the tasks GetTasksByPeriod returns, per user. Users
+// without tasks get empty totals.
+func (r *TaskRepository) GetTotals(ctx context.Context, userIDs []int, start, end time.Time) (map[int]*models.TaskTotals, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT user_id,
+		       description,
+		       to_char(start_time AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+		       count(*),
+		       sum(EXTRACT(EPOCH FROM (end_time - start_time)))::bigint
+		FROM tasks
+		WHERE user_id = ANY ($1) AND start_time >= $2 AND end_time <= $3 AND deleted_at IS NULL
+		GROUP BY user_id, description, day
+		ORDER BY user_id, day, description
+	`, userIDs, start, end)
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"taskID": taskID,
-			"error":  err,
-		}).Error("An error occurred while completing the task")
-		return err
+		logger.FromContext(ctx).Error("An error occurred while summing up tasks",
+			"userIDs", userIDs,
+			"error", err,
+		)
+		return nil, err
 	}
+	defer rows.Close()
 
-	logger.Logger.WithFields(logrus.Fields{
-		"taskID": taskID,
-	}).Info("Task completed successfully")
+	totals := make(map[int]*models.TaskTotals, len(userIDs))
+	for _, id := range userIDs {
+		totals[id] = &models.TaskTotals{UserID: id, ByDescription: []models.DescriptionTotal{}, ByDay: []models.DayTotal{}}
+	}
+	descriptions := make(map[int]map[string]int)
+	for rows.Next() {
+		var (
+			userID, count    int
+			description, day string
+			seconds          int64
+		)
+		if err := rows.Scan(&userID, &description, &day, &count, &seconds); err != nil {
+			return nil, err
+		}
+		t := totals[userID]
+		t.TaskCount += count
+		t.TotalSeconds += seconds
 
-	return nil
+		// Rows come ordered by day, so a day continues the last entry.
+		if n := len(t.ByDay); n > 0 && t.ByDay[n-1].Date == day {
+			t.ByDay[n-1].TaskCount += count
+			t.ByDay[n-1].TotalSeconds += seconds
+		} else {
+			t.ByDay = append(t.ByDay, models.DayTotal{Date: day, TaskCount: count, TotalSeconds: seconds})
+		}
+
+		if descriptions[userID] == nil {
+			descriptions[userID] = make(map[string]int)
+		}
+		i, ok := descriptions[userID][description]
+		if !ok {
+			i = len(t.ByDescription)
+			descriptions[userID][description] = i
+			t.ByDescription = append(t.ByDescription, models.DescriptionTotal{Description: description})
+		}
+		t.ByDescription[i].TaskCount += count
+		t.ByDescription[i].TotalSeconds += seconds
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range totals {
+		sort.SliceStable(t.ByDescription, func(i, j int) bool {
+			return t.ByDescription[i].TotalSeconds > t.ByDescription[j].TotalSeconds
+		})
+	}
+	return totals, nil
 }
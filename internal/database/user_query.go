@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"time-tracker/internal/models"
+	"time-tracker/internal/storage"
+)
+
+// userFieldColumns maps the fields of storage.UserFields to their columns.
+var userFieldColumns = map[string]string{
+	"id":             "id",
+	"passportNumber": "passport_number",
+	"surname":        "surname",
+	"name":           "name",
+	"patronymic":     "patronymic",
+	"address":        "address",
+	"createdAt":      "created_at",
+	"updatedAt":      "updated_at",
+}
+
+// fieldExpr is the SQL expression used for filtering and ordering; text
+// columns are nullable, so NULL and the empty string are treated alike.
+func fieldExpr(name string, kind storage.FieldKind) string {
+	if kind == storage.TextField {
+		return "COALESCE(" + userFieldColumns[name] + ", '')"
+	}
+	return userFieldColumns[name]
+}
+
+var comparisonOps = map[models.FilterOp]string{
+	models.OpEq:  "=",
+	models.OpNe:  "<>",
+	models.OpGt:  ">",
+	models.OpGte: ">=",
+	models.OpLt:  "<",
+	models.OpLte: "<=",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// queryBuilder collects WHERE conditions together with their positional arguments.
+type queryBuilder struct {
+	where []string
+	args  []interface{}
+}
+
+func (b *queryBuilder) arg(val interface{}) string {
+	b.args = append(b.args, val)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+func (b *queryBuilder) addUserFilter(filter storage.Filter) {
+	expr := fieldExpr(filter.Field, filter.Kind)
+	switch {
+	case filter.Kind == storage.TextField && filter.Op == models.OpContains:
+		b.where = append(b.where, fmt.Sprintf("%s ILIKE '%%' || %s || '%%'", expr, b.arg(likeEscaper.Replace(filter.Values[0].(string)))))
+	case filter.Kind == storage.TextField && filter.Op == models.OpPrefix:
+		b.where = append(b.where, fmt.Sprintf("%s ILIKE %s || '%%'", expr, b.arg(likeEscaper.Replace(filter.Values[0].(string)))))
+	case filter.Kind == storage.TextField && filter.Op == models.OpIn:
+		values := make([]string, 0, len(filter.Values))
+		for _, val := range filter.Values {
+			values = append(values, val.(string))
+		}
+		b.where = append(b.where, fmt.Sprintf("lower(%s) IN (SELECT lower(v) FROM unnest(%s::text[]) AS v)", expr, b.arg(values)))
+	case filter.Kind == storage.TextField:
+		b.where = append(b.where, fmt.Sprintf("lower(%s) %s lower(%s)", expr, comparisonOps[filter.Op], b.arg(filter.Values[0])))
+	case filter.Op == models.OpIn:
+		ids := make([]int, 0, len(filter.Values))
+		for _, val := range filter.Values {
+			ids = append(ids, val.(int))
+		}
+		b.where = append(b.where, fmt.Sprintf("%s = ANY(%s)", expr, b.arg(ids)))
+	default:
+		b.where = append(b.where, fmt.Sprintf("%s %s %s", expr, comparisonOps[filter.Op], b.arg(filter.Values[0])))
+	}
+}
+
+func orderByClause(keys []storage.SortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		dir := "ASC"
+		if key.Desc {
+			dir = "DESC"
+		}
+		parts = append(parts, fieldExpr(key.Field, key.Kind)+" "+dir)
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// addKeyset restricts the query to rows after the cursor position:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., flipping the comparison for
+// descending keys.
+func (b *queryBuilder) addKeyset(keys []storage.SortKey, after []interface{}) {
+	placeholders := make([]string, len(keys))
+	for i := range keys {
+		placeholders[i] = b.arg(after[i])
+	}
+
+	alternatives := make([]string, 0, len(keys))
+	for i, key := range keys {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = %s", fieldExpr(keys[j].Field, keys[j].Kind), placeholders[j]))
+		}
+		cmp := ">"
+		if key.Desc {
+			cmp = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s %s", fieldExpr(key.Field, key.Kind), cmp, placeholders[i]))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	b.where = append(b.where, "("+strings.Join(alternatives, " OR ")+")")
+}
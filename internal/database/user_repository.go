@@ -1,18 +1,23 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 
 	"context"
 	"time"
 
+	"time-tracker/internal/apperrors"
 	"time-tracker/internal/logger"
 	"time-tracker/internal/models"
+	"time-tracker/internal/storage"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/sirupsen/logrus"
 )
 
+const userColumns = "id, passport_number, surname, name, patronymic, address, created_at, updated_at, deleted_at, version"
+
 type UserRepository struct {
 	db *pgxpool.Pool
 }
@@ -21,158 +26,562 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+func scanUser(row pgx.Row, user *models.User) error {
+	return row.Scan(&user.ID, &user.PassportNumber, &user.Surname, &user.Name, &user.Patronymic, &user.Address, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.Version)
+}
+
+// lockUser reads the user row and locks it until the end of the transaction.
+func lockUser(ctx context.Context, tx pgx.Tx, id int, includeDeleted bool) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id=$1`
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
+	user := &models.User{}
+	err := scanUser(tx.QueryRow(ctx, query+" FOR UPDATE", id), user)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &apperrors.NoUserError{Message: fmt.Sprintf("User with id %v doesn't exist", id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func userChangedError(current *models.User) error {
+	return &apperrors.PreconditionFailedError{Message: fmt.Sprintf("User with id %v has been modified, current version is %v", current.ID, current.Version)}
+}
+
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
-	logger.Logger.WithFields(logrus.Fields{
-		"passport_number": user.PassportNumber,
-		"surname":         user.Surname,
-		"name":            user.Name,
-		"patronymic":      user.Patronymic,
-		"address":         user.Address,
-	}).Debug("Создание юзера")
-
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = user.CreatedAt
-	query := `INSERT INTO users (passport_number, surname, name, patronymic, address, created_at, updated_at) 
-              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
-	err := r.db.QueryRow(ctx, query, user.PassportNumber, user.Surname, user.Name, user.Patronymic, user.Address, user.CreatedAt, user.UpdatedAt).Scan(&user.ID)
+	logger.FromContext(ctx).Debug("Создание юзера",
+		"passport_number", user.PassportNumber,
+		"surname", user.Surname,
+		"name", user.Name,
+		"patronymic", user.Patronymic,
+		"address", user.Address,
+	)
+
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `INSERT INTO users (passport_number, surname, name, patronymic, address, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, NOW(), NOW()) RETURNING ` + userColumns
+		if err := scanUser(tx.QueryRow(ctx, query, user.PassportNumber, user.Surname, user.Name, user.Patronymic, user.Address), user); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditActionCreate, AuditEntityUser, user.ID, nil, user); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, models.EventUserCreated, AuditEntityUser, user.ID, user)
+	})
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("An error occurred while creating a user")
+		logger.FromContext(ctx).Error("An error occurred while creating a user",
+			"error", err,
+		)
+		return err
 	}
 
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": user.ID,
-	}).Info("The user has been created")
+	logger.FromContext(ctx).Info("The user has been created",
+		"userID", user.ID,
+	)
 
 	return nil
 }
 
-func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": id,
-	}).Debug("Getting a user by ID")
+func (r *UserRepository) GetUserByID(ctx context.Context, id int, includeDeleted bool) (*models.User, error) {
+	logger.FromContext(ctx).Debug("Getting a user by ID",
+		"userID", id,
+		"includeDeleted", includeDeleted,
+	)
 
 	user := &models.User{}
-	query := `SELECT id, passport_number, surname, name, patronymic, address, created_at, updated_at FROM users WHERE id=$1`
-	err := r.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.PassportNumber, &user.Surname, &user.Name, &user.Patronymic, &user.Address, &user.CreatedAt, &user.UpdatedAt)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id=$1`
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	err := scanUser(r.db.QueryRow(ctx, query, id), user)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &apperrors.NoUserError{Message: fmt.Sprintf("User with id %v doesn't exist", id)}
+	}
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID": id,
-			"error":  err,
-		}).Error("An error occurred while retrieving the user's ID")
+		logger.FromContext(ctx).Error("An error occurred while retrieving the user's ID",
+			"userID", id,
+			"error", err,
+		)
 		return nil, err
 	}
 
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": id,
-	}).Info("User data successfully received")
+	logger.FromContext(ctx).Info("User data successfully received",
+		"userID", id,
+	)
 
 	return user, nil
 
 }
 
-func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
-	logger.Logger.WithFields(logrus.Fields{
-		"userID":          user.ID,
-		"passport_number": user.PassportNumber,
-		"username":        user.Surname,
-		"name":            user.Name,
-		"patronymic":      user.Patronymic,
-		"address":         user.Address,
-	}).Debug("Updating user data")
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User, cond models.Precondition) error {
+	logger.FromContext(ctx).Debug("Updating user data",
+		"userID", user.ID,
+		"passport_number", user.PassportNumber,
+		"username", user.Surname,
+		"name", user.Name,
+		"patronymic", user.Patronymic,
+		"address", user.Address,
+	)
 
-	user.UpdatedAt = time.Now()
-	query := `UPDATE users SET passport_number=$1, surname=$2, name=$3, patronymic=$4, address=$5, updated_at=$6 WHERE id=$7`
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockUser(ctx, tx, user.ID, false)
+		if err != nil {
+			return err
+		}
+		if !cond.Matches(before.Version) {
+			return userChangedError(before)
+		}
 
-	_, err := r.db.Exec(ctx, query, user.PassportNumber, user.Surname, user.Name, user.Patronymic, user.Address, user.UpdatedAt, user.ID)
+		query := `UPDATE users SET passport_number=$1, surname=$2, name=$3, patronymic=$4, address=$5, updated_at=NOW(), version=version+1 WHERE id=$6 RETURNING ` + userColumns
+		if err := scanUser(tx.QueryRow(ctx, query, user.PassportNumber, user.Surname, user.Name, user.Patronymic, user.Address, user.ID), user); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditActionUpdate, AuditEntityUser, user.ID, before, user); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, models.EventUserUpdated, AuditEntityUser, user.ID, user)
+	})
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID": user.ID,
-			"error":  err,
-		}).Error("An error occurred while updating user data")
+		logger.FromContext(ctx).Error("An error occurred while updating user data",
+			"userID", user.ID,
+			"error", err,
+		)
 		return err
 	}
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": user.ID,
-	}).Info("User data has been successfully updated")
+	logger.FromContext(ctx).Info("User data has been successfully updated",
+		"userID", user.ID,
+	)
 	return nil
 }
 
-func (r *UserRepository) DeleteUser(ctx context.Context, id int) error {
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": id,
-	}).Debug("Deleting a user")
+// PatchUser locks the user, lets apply modify it and stores the result, so
+// that concurrent patches never work on a stale copy.
+func (r *UserRepository) PatchUser(ctx context.Context, id int, cond models.Precondition, apply func(user *models.User) error) (*models.User, error) {
+	logger.FromContext(ctx).Debug("Patching user data",
+		"userID", id,
+	)
+
+	user := &models.User{}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockUser(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		if !cond.Matches(before.Version) {
+			return userChangedError(before)
+		}
+
+		patched := *before
+		if err := apply(&patched); err != nil {
+			return err
+		}
 
-	query := `DELETE FROM users WHERE id=$1`
-	_, err := r.db.Exec(ctx, query, id)
+		query := `UPDATE users SET passport_number=$1, surname=$2, name=$3, patronymic=$4, address=$5, updated_at=NOW(), version=version+1 WHERE id=$6 RETURNING ` + userColumns
+		if err := scanUser(tx.QueryRow(ctx, query, patched.PassportNumber, patched.Surname, patched.Name, patched.Patronymic, patched.Address, id), user); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditActionUpdate, AuditEntityUser, id, before, user); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, models.EventUserUpdated, AuditEntityUser, id, user)
+	})
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"userID": id,
-			"error":  err,
-		}).Error("An error occurred when deleting a user")
+		logger.FromContext(ctx).Error("An error occurred while patching user data",
+			"userID", id,
+			"error", err,
+		)
+		return nil, err
+	}
+	logger.FromContext(ctx).Info("User data has been successfully patched",
+		"userID", id,
+	)
+	return user, nil
+}
+
+// DeleteUser soft-deletes the user together with their tasks. The rows are
+// kept until the purge job removes them after the retention period.
+func (r *UserRepository) DeleteUser(ctx context.Context, id int, cond models.Precondition) error {
+	logger.FromContext(ctx).Debug("Deleting a user",
+		"userID", id,
+	)
+
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockUser(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		if !cond.Matches(before.Version) {
+			return userChangedError(before)
+		}
+
+		after := &models.User{}
+		if err := scanUser(tx.QueryRow(ctx, `UPDATE users SET deleted_at = NOW(), version = version + 1 WHERE id=$1 RETURNING `+userColumns, id), after); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE tasks SET deleted_at = $2, version = version + 1 WHERE user_id=$1 AND deleted_at IS NULL`, id, *after.DeletedAt); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditActionDelete, AuditEntityUser, id, before, after); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, models.EventUserDeleted, AuditEntityUser, id, after)
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred when deleting a user",
+			"userID", id,
+			"error", err,
+		)
 		return err
 	}
-	logger.Logger.WithFields(logrus.Fields{
-		"userID": id,
-	}).Info("The user was successfully deleted")
+	logger.FromContext(ctx).Info("The user was successfully deleted",
+		"userID", id,
+	)
 
 	return nil
 }
 
-func (r *UserRepository) GetUsers(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]models.User, error) {
-	logger.Logger.WithFields(logrus.Fields{
-		"filter": filter,
-		"limit":  limit,
-		"offset": offset,
-	}).Debug("Obtaining users with the ability to filter and paginate")
+// RestoreUser undoes a soft delete, bringing back the tasks that were deleted along with the user.
+func (r *UserRepository) RestoreUser(ctx context.Context, id int) (*models.User, error) {
+	logger.FromContext(ctx).Debug("Restoring a user",
+		"userID", id,
+	)
 
-	var argID int = 1
-	query := "SELECT id, passport_number, surname, name, patronymic, address, created_at, updated_at FROM users WHERE true"
-	args := []interface{}{}
+	user := &models.User{}
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		before, err := lockUser(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		if before.DeletedAt == nil {
+			*user = *before
+			return nil
+		}
 
-	for key, val := range filter {
-		query += fmt.Sprintf(" AND %s = $%d", key, argID)
-		args = append(args, val)
-		argID++
+		if err := scanUser(tx.QueryRow(ctx, `UPDATE users SET deleted_at = NULL, version = version + 1 WHERE id=$1 RETURNING `+userColumns, id), user); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE tasks SET deleted_at = NULL, version = version + 1 WHERE user_id=$1 AND deleted_at = $2`, id, *before.DeletedAt); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditActionRestore, AuditEntityUser, id, before, user)
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred when restoring a user",
+			"userID", id,
+			"error", err,
+		)
+		return nil, err
 	}
+	logger.FromContext(ctx).Info("The user was successfully restored",
+		"userID", id,
+	)
+
+	return user, nil
+}
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argID, argID+1)
-	args = append(args, limit, offset)
-	rows, err := r.db.Query(ctx, query, args...)
+// GetUsersByIDs returns the users with the given IDs that exist and are not
+// deleted, in no particular order.
+func (r *UserRepository) GetUsersByIDs(ctx context.Context, ids []int) ([]models.User, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+userColumns+` FROM users WHERE id = ANY ($1) AND deleted_at IS NULL`, ids)
 	if err != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("An error occurred while retrieving users")
+		logger.FromContext(ctx).Error("An error occurred while retrieving users by ID",
+			"userIDs", ids,
+			"error", err,
+		)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var users []models.User
+	users := []models.User{}
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.PassportNumber, &user.Surname, &user.Name, &user.Patronymic, &user.Address, &user.CreatedAt, &user.UpdatedAt); err != nil {
-			logger.Logger.WithFields(logrus.Fields{
-				"error": err,
-			}).Error("An error occurred while scanning user strings")
+		if err := scanUser(rows, &user); err != nil {
 			return nil, err
 		}
-
 		users = append(users, user)
 	}
+	return users, rows.Err()
+}
+
+// PurgeDeleted hard-deletes users and tasks that were soft-deleted before the given moment.
+func (r *UserRepository) PurgeDeleted(ctx context.Context, before time.Time) (users, tasks int64, err error) {
+	logger.FromContext(ctx).Debug("Purging soft-deleted users and tasks",
+		"before", before,
+	)
+
+	err = pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			DELETE FROM tasks
+			WHERE deleted_at < $1
+			   OR user_id IN (SELECT id FROM users WHERE deleted_at < $1)
+		`, before)
+		if err != nil {
+			return err
+		}
+		tasks = tag.RowsAffected()
+
+		tag, err = tx.Exec(ctx, `DELETE FROM users WHERE deleted_at < $1`, before)
+		if err != nil {
+			return err
+		}
+		users = tag.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while purging soft-deleted rows",
+			"before", before,
+			"error", err,
+		)
+		return 0, 0, err
+	}
+
+	logger.FromContext(ctx).Info("Soft-deleted rows have been purged",
+		"before", before,
+		"users", users,
+		"tasks", tasks,
+	)
+
+	return users, tasks, nil
+}
+
+// GetUsers returns one page of users matching the query together with the
+// total number of matches and the cursor of the next page.
+func (r *UserRepository) GetUsers(ctx context.Context, query models.UserQuery) (*models.UserPage, error) {
+	logger.FromContext(ctx).Debug("Obtaining users with the ability to filter and paginate",
+		"query", query,
+	)
+
+	listing, err := storage.ResolveUserQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var b queryBuilder
+	if !query.IncludeDeleted {
+		b.where = append(b.where, "deleted_at IS NULL")
+	}
+	for _, filter := range listing.Filters {
+		b.addUserFilter(filter)
+	}
+
+	page := &models.UserPage{Users: []models.User{}}
+	if err := r.db.QueryRow(ctx, "SELECT count(*) FROM users"+b.whereClause(), b.args...).Scan(&page.Total); err != nil {
+		logger.FromContext(ctx).Error("An error occurred while counting users",
+			"error", err,
+		)
+		return nil, err
+	}
+
+	if listing.After != nil {
+		b.addKeyset(listing.Keys, listing.After)
+	}
+	sql := "SELECT " + userColumns + " FROM users" + b.whereClause() + orderByClause(listing.Keys) + " LIMIT " + b.arg(query.Limit+1)
+
+	rows, err := r.db.Query(ctx, sql, b.args...)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while retrieving users",
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			logger.FromContext(ctx).Error("An error occurred while scanning user strings",
+				"error", err,
+			)
+			return nil, err
+		}
+
+		page.Users = append(page.Users, user)
+	}
+	if rows.Err() != nil {
+		logger.FromContext(ctx).Error("An error occurred when trying to iterate over rows with users",
+			"error", rows.Err(),
+		)
+		return nil, rows.Err()
+	}
+
+	if len(page.Users) > query.Limit {
+		page.Users = page.Users[:query.Limit]
+		page.Next = storage.EncodeUserCursor(listing.Keys, &page.Users[query.Limit-1])
+	}
+
+	logger.FromContext(ctx).Info("User information successfully received",
+		"query", query,
+		"count", len(page.Users),
+		"total", page.Total,
+	)
+
+	return page, nil
+}
+
+func (r *UserRepository) ExportUser(ctx context.Context, id int) (*models.UserExport, error) {
+	logger.FromContext(ctx).Debug("Exporting user personal data",
+		"userID", id,
+	)
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while starting the export transaction",
+			"userID", id,
+			"error", err,
+		)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	export := &models.UserExport{Tasks: []models.Task{}}
+	err = scanUser(tx.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id=$1`, id), &export.User)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &apperrors.NoUserError{Message: fmt.Sprintf("User with id %v doesn't exist", id)}
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while reading the user for export",
+			"userID", id,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+	SELECT `+taskColumns+`
+	FROM tasks
+	WHERE user_id = $1
+	ORDER BY start_time
+	`, id)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while reading the user's tasks for export",
+			"userID", id,
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			logger.FromContext(ctx).Error("An error occurred while scanning the task line",
+				"error", err,
+			)
+			return nil, err
+		}
+		export.Tasks = append(export.Tasks, task)
+	}
 	if rows.Err() != nil {
-		logger.Logger.WithFields(logrus.Fields{
-			"error": rows.Err(),
-		}).Error("An error occurred when trying to iterate over rows with users")
+		logger.FromContext(ctx).Error("An error occurred while iterating through the data rows",
+			"error", rows.Err(),
+		)
 		return nil, rows.Err()
 	}
+	export.ExportedAt = time.Now()
+
+	logger.FromContext(ctx).Info("User personal data exported",
+		"userID", id,
+		"tasks", len(export.Tasks),
+	)
+
+	return export, nil
+}
+
+// EraseUser anonymises the user's personal data and removes their tasks. The
+// time records of ended tasks can be kept for accounting, added to the daily
+// totals of erased users.
+// Personal data is also scrubbed from the user's audit trail, outbox events,
+// webhook deliveries and the responses recorded for Idempotency-Keys.
+func (r *UserRepository) EraseUser(ctx context.Context, id int, req *models.EraseRequest) (*models.ErasureRecord, error) {
+	logger.FromContext(ctx).Debug("Erasing user personal data",
+		"userID", id,
+		"requestedBy", req.RequestedBy,
+		"retainTimeRecords", req.RetainTimeRecords,
+	)
+
+	record := &models.ErasureRecord{
+		UserID:              id,
+		RequestedBy:         req.RequestedBy,
+		Reason:              req.Reason,
+		RetainedTimeRecords: req.RetainTimeRecords,
+	}
+
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := lockUser(ctx, tx, id, true); err != nil {
+			return err
+		}
+
+		if req.RetainTimeRecords {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO erased_time_totals (day, tasks, duration_seconds)
+				SELECT (start_time AT TIME ZONE 'UTC')::date, count(*), sum(extract(epoch FROM end_time - start_time))::bigint
+				FROM tasks
+				WHERE user_id = $1 AND end_time IS NOT NULL
+				GROUP BY 1
+				ON CONFLICT (day) DO UPDATE
+				SET tasks = erased_time_totals.tasks + EXCLUDED.tasks,
+				    duration_seconds = erased_time_totals.duration_seconds + EXCLUDED.duration_seconds
+			`, id)
+			if err != nil {
+				return err
+			}
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM tasks WHERE user_id = $1`, id)
+		if err != nil {
+			return err
+		}
+		record.TasksAffected = int(tag.RowsAffected())
+
+		_, err = tx.Exec(ctx, `
+			UPDATE users
+			SET passport_number = '', surname = '', name = '', patronymic = '', address = '', updated_at = NOW(), version = version + 1
+			WHERE id = $1
+		`, id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO erasure_requests (user_id, requested_by, reason, retained_time_records, tasks_affected, created_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			RETURNING id, created_at
+		`, id, record.RequestedBy, record.Reason, record.RetainedTimeRecords, record.TasksAffected).Scan(&record.ID, &record.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := scrubAudit(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := scrubDeliveries(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := scrubOutbox(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := scrubIdempotency(ctx, tx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditActionErase, AuditEntityUser, id, nil, nil)
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while erasing user personal data",
+			"userID", id,
+			"error", err,
+		)
+		return nil, err
+	}
 
-	logger.Logger.WithFields(logrus.Fields{
-		"filter": filter,
-		"limit":  limit,
-		"offset": offset,
-		"count":  len(users),
-	}).Info("User information successfully received")
+	logger.FromContext(ctx).Info("User personal data has been erased",
+		"userID", id,
+		"requestedBy", record.RequestedBy,
+		"tasksAffected", record.TasksAffected,
+	)
 
-	return users, nil
+	return record, nil
 }
@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const deliveryColumns = "id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func scanDelivery(row pgx.Row, d *models.WebhookDelivery) error {
+	var next time.Time
+	if err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &next, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+		return err
+	}
+	if d.Status == models.DeliveryPending {
+		d.NextAttemptAt = &next
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, req *models.WebhookRequest) (*models.WebhookSubscription, error) {
+	sub := &models.WebhookSubscription{}
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING id, url, secret, event_types, active, created_at
+	`, req.URL, req.Secret, req.EventTypes).Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.EventTypes, &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while creating a webhook subscription",
+			"url", req.URL,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Webhook subscription has been created",
+		"subscriptionID", sub.ID,
+		"url", sub.URL,
+		"eventTypes", sub.EventTypes,
+	)
+	return sub, nil
+}
+
+func (r *WebhookRepository) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, url, secret, event_types, active, created_at FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while retrieving webhook subscriptions",
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.EventTypes, &sub.Active, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// DeleteSubscription removes a subscription together with its delivery log.
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while deleting a webhook subscription",
+			"subscriptionID", id,
+			"error", err,
+		)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return &apperrors.NotFoundError{Message: fmt.Sprintf("Webhook subscription with id %v doesn't exist", id)}
+	}
+
+	logger.FromContext(ctx).Info("Webhook subscription has been deleted",
+		"subscriptionID", id,
+	)
+	return nil
+}
+
+// GetDeliveries returns the latest deliveries of a subscription, newest first.
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID, limit int) ([]models.WebhookDelivery, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)`, subscriptionID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &apperrors.NotFoundError{Message: fmt.Sprintf("Webhook subscription with id %v doesn't exist", subscriptionID)}
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, subscriptionID, limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while retrieving webhook deliveries",
+			"subscriptionID", subscriptionID,
+			"error", err,
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redeliver queues the event of a past delivery again as a new delivery,
+// leaving the original entry of the log untouched.
+func (r *WebhookRepository) Redeliver(ctx context.Context, deliveryID int64) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	err := scanDelivery(r.db.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, redelivery_of)
+		SELECT subscription_id, event_id, event_type, payload, id
+		FROM webhook_deliveries
+		WHERE id = $1
+		RETURNING `+deliveryColumns, deliveryID), d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &apperrors.NotFoundError{Message: fmt.Sprintf("Webhook delivery with id %v doesn't exist", deliveryID)}
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("An error occurred while queueing a redelivery",
+			"deliveryID", deliveryID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Webhook delivery has been queued again",
+		"deliveryID", deliveryID,
+		"newDeliveryID", d.ID,
+	)
+	return d, nil
+}
+
+// ClaimDue picks up to limit due deliveries. Claimed rows are pushed back by
+// lease so that other instances skip them until RecordAttempt is called or
+// the lease runs out.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.OutgoingWebhook, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret
+	`, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []models.OutgoingWebhook
+	for rows.Next() {
+		var w models.OutgoingWebhook
+		if err := rows.Scan(&w.DeliveryID, &w.EventID, &w.EventType, &w.Payload, &w.Attempts, &w.URL, &w.Secret); err != nil {
+			return nil, err
+		}
+		due = append(due, w)
+	}
+	return due, rows.Err()
+}
+
+// RecordAttempt stores the outcome of a delivery attempt.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, deliveryID int64, attempt models.WebhookAttempt) error {
+	status := models.DeliveryPending
+	switch {
+	case attempt.Delivered:
+		status = models.DeliveryDelivered
+	case attempt.NextAttemptAt == nil:
+		status = models.DeliveryFailed
+	}
+	var statusCode *int
+	if attempt.StatusCode != 0 {
+		statusCode = &attempt.StatusCode
+	}
+
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2,
+		    attempts = attempts + 1,
+		    last_status_code = $3,
+		    last_error = $4,
+		    next_attempt_at = COALESCE($5, next_attempt_at),
+		    delivered_at = CASE WHEN $2 = 'delivered' THEN now() END
+		WHERE id = $1
+	`, deliveryID, status, statusCode, attempt.Error, attempt.NextAttemptAt)
+	return err
+}
+
+// Enqueue queues an outbox event for every active subscription to its type.
+// An event relayed twice is queued only once per subscription.
+func (r *WebhookRepository) Enqueue(ctx context.Context, event models.OutboxEvent) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3
+		FROM webhook_subscriptions
+		WHERE active AND $2::text = ANY (event_types)
+		ON CONFLICT (subscription_id, event_id) WHERE redelivery_of IS NULL DO NOTHING
+	`, event.EventID, event.Type, event.Payload)
+	return err
+}
+
+// PurgeDeliveries deletes finished deliveries created before the given moment.
+func (r *WebhookRepository) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// scrubbedEventPayload rewrites the payload of a user or task event the way
+// the user or task serialises once erased.
+const scrubbedEventPayload = `jsonb_set(payload, '{data}', CASE
+	WHEN event_type LIKE 'user.%' THEN (payload->'data') - '{surname,name,patronymic,address}'::text[] || '{"passportNumber": ""}'
+	ELSE (payload->'data') || '{"description": ""}'
+END)`
+
+// eventOfUser matches the user and task events of the user given as $1.
+const eventOfUser = `((event_type LIKE 'user.%' AND payload->'data'->>'id' = $1) OR (event_type LIKE 'task.%' AND payload->'data'->>'userId' = $1))`
+
+// scrubDeliveries removes personal data from the queued and logged webhook
+// deliveries of an erased user.
+func scrubDeliveries(ctx context.Context, tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(ctx, `UPDATE webhook_deliveries SET payload = `+scrubbedEventPayload+` WHERE `+eventOfUser, strconv.Itoa(userID))
+	return err
+}
@@ -0,0 +1,82 @@
+package etag
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Format renders the entity version as a strong entity tag.
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch turns an If-Match header into a write precondition. An absent
+// header and "*" match any version.
+func ParseIfMatch(header string) (models.Precondition, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return models.Precondition{}, nil
+	}
+
+	var cond models.Precondition
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			// Weak tags never match under the strong comparison If-Match
+			// requires; version 0 is never assigned to a row.
+			cond.Versions = append(cond.Versions, 0)
+			continue
+		}
+		version, err := parse(tag)
+		if err != nil {
+			return models.Precondition{}, err
+		}
+		cond.Versions = append(cond.Versions, version)
+	}
+	return cond, nil
+}
+
+// NotModified reports whether the If-None-Match header of the request matches
+// the current version, using the weak comparison.
+func NotModified(c *gin.Context, version int) bool {
+	header := strings.TrimSpace(c.GetHeader("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if v, err := parse(tag); err == nil && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Require rejects writes without an If-Match header with 428 Precondition
+// Required when strict mode is on.
+func Require(strict bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strict && c.GetHeader("If-Match") == "" {
+			c.Error(&apperrors.PreconditionRequiredError{Message: "If-Match header is required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func parse(tag string) (int, error) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, errors.New("malformed entity tag")
+	}
+	return strconv.Atoi(tag[1 : len(tag)-1])
+}
@@ -0,0 +1,104 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    int
+		wantErr bool
+	}{
+		{tag: `"7"`, want: 7},
+		{tag: `"0"`, want: 0},
+		{tag: `7`, wantErr: true},
+		{tag: `"7`, wantErr: true},
+		{tag: `7"`, wantErr: true},
+		{tag: `"`, wantErr: true},
+		{tag: `""`, wantErr: true},
+		{tag: `"abc"`, wantErr: true},
+		{tag: `W/"7"`, wantErr: true},
+		{tag: ``, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parse(tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parse(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parse(%q) = %d, want %d", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    []int
+		wantErr bool
+	}{
+		{name: "absent", header: "", want: nil},
+		{name: "blank", header: "   ", want: nil},
+		{name: "any", header: "*", want: nil},
+		{name: "any padded", header: " * ", want: nil},
+		{name: "strong", header: `"3"`, want: []int{3}},
+		{name: "weak never matches", header: `W/"3"`, want: []int{0}},
+		{name: "list", header: `"1", "2",W/"3"`, want: []int{1, 2, 0}},
+		{name: "unquoted", header: `3`, wantErr: true},
+		{name: "garbage", header: `"abc"`, wantErr: true},
+		{name: "garbage in list", header: `"1", nope`, wantErr: true},
+		{name: "empty list item", header: `"1",`, wantErr: true},
+		{name: "any in list", header: `"1", *`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIfMatch(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIfMatch(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+			if !slices.Equal(got.Versions, tt.want) {
+				t.Errorf("ParseIfMatch(%q) = %v, want %v", tt.header, got.Versions, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "absent", header: "", want: false},
+		{name: "any", header: "*", want: true},
+		{name: "same", header: `"5"`, want: true},
+		{name: "other", header: `"4"`, want: false},
+		{name: "weak same", header: `W/"5"`, want: true},
+		{name: "list", header: `"3", W/"5"`, want: true},
+		{name: "list without match", header: `"3", "4"`, want: false},
+		{name: "unquoted", header: `5`, want: false},
+		{name: "garbage then match", header: `nope, "5"`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-None-Match", tt.header)
+			}
+			if got := NotModified(c, 5); got != tt.want {
+				t.Errorf("NotModified(%q, 5) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
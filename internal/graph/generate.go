@@ -0,0 +1,3 @@
+package graph
+
+//go:generate go run github.com/99designs/gqlgen@v0.17.49 generate
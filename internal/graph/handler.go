@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/config"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/problem"
+	"time-tracker/internal/requestid"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Estimated sizes of the lists whose length is not known before they are
+// resolved, used to weigh their fields against the complexity limit.
+const (
+	tasksPerPeriod        = 20
+	descriptionsPerPeriod = 10
+	daysPerPeriod         = 31
+)
+
+// NewHandler serves GraphQL queries over POST; GET /graphql is left to the
+// playground in dev mode. Queries over the complexity limit are rejected
+// before they run. Introspection is only enabled in dev mode.
+func NewHandler(userRepo *db.UserRepository, taskRepo *db.TaskRepository, cfg config.GraphQLConfig) http.Handler {
+	schema := NewExecutableSchema(Config{
+		Resolvers:  &Resolver{userRepo: userRepo, taskRepo: taskRepo},
+		Complexity: complexity(),
+	})
+
+	srv := handler.New(schema)
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New(1000))
+	srv.Use(extension.FixedComplexityLimit(cfg.ComplexityLimit))
+	if cfg.Dev {
+		srv.Use(extension.Introspection{})
+	}
+	srv.SetErrorPresenter(presentError)
+	srv.SetRecoverFunc(recoverPanic)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := withLoaders(r.Context(), newLoaders(userRepo, taskRepo))
+		srv.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Playground serves GraphiQL against endpoint.
+func Playground(endpoint string) http.Handler {
+	return playground.Handler("Time tracker", endpoint)
+}
+
+// complexity weighs lists by the number of items they are expected to hold,
+// so that nesting tasks under a page of users costs what it does.
+func complexity() ComplexityRoot {
+	var c ComplexityRoot
+	c.Query.Users = func(childComplexity int, _ []UserFilter, _ *string, first *int, _ *string) int {
+		return 1 + pageSize(first)*childComplexity
+	}
+	c.User.Tasks = func(childComplexity int, _, _ time.Time) int {
+		return 1 + tasksPerPeriod*childComplexity
+	}
+	c.Totals.ByDescription = func(childComplexity int) int {
+		return 1 + descriptionsPerPeriod*childComplexity
+	}
+	c.Totals.ByDay = func(childComplexity int) int {
+		return 1 + daysPerPeriod*childComplexity
+	}
+	return c
+}
+
+// presentError maps the errors of resolvers the way problem.Middleware does
+// for REST: the message of an apperror is kept, anything else is replaced by
+// a generic one. The problem type and status go into the extensions. Errors
+// of the query itself, such as syntax errors, are left as they are.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	cause := gqlErr.Unwrap()
+	if cause == nil {
+		return gqlErr
+	}
+	if errors.Is(cause, context.Canceled) {
+		gqlErr.Message = "The request was cancelled"
+		return gqlErr
+	}
+	// The schema fields only fail when introspection is disabled.
+	if fc := graphql.GetFieldContext(ctx); fc != nil && strings.HasPrefix(fc.Field.Name, "__") {
+		cause = &apperrors.ForbiddenError{Message: "Introspection is disabled"}
+	}
+
+	p := problem.FromError(cause)
+	if p.Status >= http.StatusInternalServerError {
+		logger.FromContext(ctx).Error("Request failed",
+			"path", gqlErr.Path.String(),
+			"status", p.Status,
+			"error", cause,
+		)
+	}
+
+	gqlErr.Message = p.Detail
+	gqlErr.Extensions = map[string]interface{}{
+		"code":      strings.TrimPrefix(p.Type, "/problems/"),
+		"status":    p.Status,
+		"requestId": requestid.FromContext(ctx),
+	}
+	if len(p.Errors) > 0 {
+		gqlErr.Extensions["errors"] = p.Errors
+	}
+	return gqlErr
+}
+
+func recoverPanic(ctx context.Context, recovered interface{}) error {
+	logger.FromContext(ctx).Error("Panic while resolving a field",
+		"panic", fmt.Sprint(recovered),
+	)
+	return errors.New("panic while resolving a field")
+}
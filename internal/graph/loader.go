@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// batchWait is how long a batch collects keys before it is fetched.
+	// Resolvers of sibling fields run concurrently, so a short wait is
+	// enough for a whole list of users to ask for their tasks.
+	batchWait = 2 * time.Millisecond
+	// maxBatch fetches a batch right away once it holds this many keys.
+	maxBatch = 100
+)
+
+// loader turns the loads of single keys into batched fetches, the way
+// dataloader does. Results are cached, so a key is fetched once per request.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	batches map[K]*batch[K, V]
+	pending *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	done    chan struct{}
+	results map[K]V
+	err     error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, batches: make(map[K]*batch[K, V])}
+}
+
+// Load returns the value of key, or the zero value when the fetch did not
+// return one.
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		b = l.pending
+		if b == nil {
+			b = &batch[K, V]{done: make(chan struct{})}
+			l.pending = b
+			time.AfterFunc(batchWait, func() { l.dispatch(ctx, b) })
+		}
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+	}
+	full := len(b.keys) >= maxBatch
+	l.mu.Unlock()
+
+	if full {
+		l.dispatch(ctx, b)
+	}
+	select {
+	case <-b.done:
+		return b.results[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches a batch unless it has been fetched already.
+func (l *loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+
+	b.results, b.err = l.fetch(ctx, b.keys)
+	close(b.done)
+}
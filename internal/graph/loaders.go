@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	db "time-tracker/internal/database"
+	"time-tracker/internal/models"
+)
+
+type loadersKey struct{}
+
+// period is the key of the loaders of fields taking a start and an end.
+type period struct {
+	start, end time.Time
+}
+
+// loaders batch the lookups resolvers make for every user or task of a
+// response into one query each. They live as long as a request.
+type loaders struct {
+	userRepo *db.UserRepository
+	taskRepo *db.TaskRepository
+
+	users   *loader[int, *models.User]
+	running *loader[int, *models.Task]
+
+	mu     sync.Mutex
+	tasks  map[period]*loader[int, []models.Task]
+	totals map[period]*loader[int, *models.TaskTotals]
+}
+
+func newLoaders(userRepo *db.UserRepository, taskRepo *db.TaskRepository) *loaders {
+	l := &loaders{
+		userRepo: userRepo,
+		taskRepo: taskRepo,
+		tasks:    make(map[period]*loader[int, []models.Task]),
+		totals:   make(map[period]*loader[int, *models.TaskTotals]),
+	}
+	l.users = newLoader(l.fetchUsers)
+	l.running = newLoader(l.fetchRunningTasks)
+	return l
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func (l *loaders) fetchUsers(ctx context.Context, ids []int) (map[int]*models.User, error) {
+	users, err := l.userRepo.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	return byID, nil
+}
+
+// fetchRunningTasks keeps the latest task of a user with several running.
+func (l *loaders) fetchRunningTasks(ctx context.Context, userIDs []int) (map[int]*models.Task, error) {
+	tasks, err := l.taskRepo.GetRunningTasks(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[int]*models.Task, len(tasks))
+	for i := range tasks {
+		if current, ok := byUser[tasks[i].UserID]; !ok || tasks[i].StartTime.After(current.StartTime) {
+			byUser[tasks[i].UserID] = &tasks[i]
+		}
+	}
+	return byUser, nil
+}
+
+func (l *loaders) tasksOf(p period) *loader[int, []models.Task] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if tl, ok := l.tasks[p]; ok {
+		return tl
+	}
+	tl := newLoader(func(ctx context.Context, userIDs []int) (map[int][]models.Task, error) {
+		tasks, err := l.taskRepo.GetTasksByPeriod(ctx, userIDs, p.start, p.end)
+		if err != nil {
+			return nil, err
+		}
+		byUser := make(map[int][]models.Task, len(userIDs))
+		for _, id := range userIDs {
+			byUser[id] = []models.Task{}
+		}
+		for _, task := range tasks {
+			byUser[task.UserID] = append(byUser[task.UserID], task)
+		}
+		return byUser, nil
+	})
+	l.tasks[p] = tl
+	return tl
+}
+
+func (l *loaders) totalsOf(p period) *loader[int, *models.TaskTotals] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if tl, ok := l.totals[p]; ok {
+		return tl
+	}
+	tl := newLoader(func(ctx context.Context, userIDs []int) (map[int]*models.TaskTotals, error) {
+		return l.taskRepo.GetTotals(ctx, userIDs, p.start, p.end)
+	})
+	l.totals[p] = tl
+	return tl
+}
@@ -0,0 +1,25 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package graph
+
+import (
+	"time-tracker/internal/models"
+)
+
+type Query struct {
+}
+
+type UserConnection struct {
+	Nodes      []models.User `json:"nodes"`
+	TotalCount int           `json:"totalCount"`
+	// Passed as after to get the next page. Null on the last page.
+	EndCursor *string `json:"endCursor,omitempty"`
+}
+
+type UserFilter struct {
+	// The field to filter on, e.g. surname or createdAt.
+	Field string `json:"field"`
+	// One of eq, ne, contains, prefix, in, gt, gte, lt and lte.
+	Op     *string  `json:"op,omitempty"`
+	Values []string `json:"values"`
+}
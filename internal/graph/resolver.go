@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/models"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// Resolver resolves the fields of the schema that don't map to a field of a
+// model. Fields of users and tasks of a list go through the loaders of the
+// request so that they cost a query per list rather than per item.
+type Resolver struct {
+	userRepo *db.UserRepository
+	taskRepo *db.TaskRepository
+}
+
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+func (r *Resolver) User() UserResolver   { return &userResolver{r} }
+func (r *Resolver) Task() TaskResolver   { return &taskResolver{r} }
+
+type queryResolver struct{ *Resolver }
+
+func (r *queryResolver) User(ctx context.Context, id int) (*models.User, error) {
+	return loadersFrom(ctx).users.Load(ctx, id)
+}
+
+func (r *queryResolver) Users(ctx context.Context, filter []UserFilter, orderBy *string, first *int, after *string) (*UserConnection, error) {
+	if first != nil && (*first < 1 || *first > maxUserPageSize) {
+		return nil, &apperrors.BadRequestError{Message: fmt.Sprintf("first must be between 1 and %d", maxUserPageSize)}
+	}
+	query := models.UserQuery{Limit: pageSize(first)}
+	if after != nil {
+		query.Cursor = *after
+	}
+	if orderBy != nil && *orderBy != "" {
+		sort, ok := models.ParseSort(*orderBy)
+		if !ok {
+			return nil, &apperrors.BadRequestError{Message: "Invalid orderBy value"}
+		}
+		query.Sort = sort
+	}
+	for _, f := range filter {
+		op := models.OpEq
+		if f.Op != nil {
+			op = models.FilterOp(*f.Op)
+		}
+		query.Filters = append(query.Filters, models.FieldFilter{Field: f.Field, Op: op, Values: f.Values})
+	}
+
+	page, err := r.userRepo.GetUsers(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	conn := &UserConnection{Nodes: page.Users, TotalCount: page.Total}
+	if page.Next != "" {
+		conn.EndCursor = &page.Next
+	}
+	return conn, nil
+}
+
+func (r *queryResolver) Task(ctx context.Context, id int) (*models.Task, error) {
+	task, err := r.taskRepo.GetTaskByID(ctx, id, false)
+	var noTask *apperrors.NoTaskError
+	if errors.As(err, &noTask) {
+		return nil, nil
+	}
+	return task, err
+}
+
+type userResolver struct{ *Resolver }
+
+func (r *userResolver) Tasks(ctx context.Context, obj *models.User, start, end time.Time) ([]models.Task, error) {
+	p, err := newPeriod(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return loadersFrom(ctx).tasksOf(p).Load(ctx, obj.ID)
+}
+
+func (r *userResolver) RunningTask(ctx context.Context, obj *models.User) (*models.Task, error) {
+	return loadersFrom(ctx).running.Load(ctx, obj.ID)
+}
+
+func (r *userResolver) Totals(ctx context.Context, obj *models.User, start, end time.Time) (*models.TaskTotals, error) {
+	p, err := newPeriod(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return loadersFrom(ctx).totalsOf(p).Load(ctx, obj.ID)
+}
+
+type taskResolver struct{ *Resolver }
+
+func (r *taskResolver) User(ctx context.Context, obj *models.Task) (*models.User, error) {
+	return loadersFrom(ctx).users.Load(ctx, obj.UserID)
+}
+
+func (r *taskResolver) DurationSeconds(ctx context.Context, obj *models.Task) (int, error) {
+	end := time.Now()
+	if obj.EndTime != nil {
+		end = *obj.EndTime
+	}
+	return int(end.Sub(obj.StartTime).Seconds()), nil
+}
+
+func (r *taskResolver) Running(ctx context.Context, obj *models.Task) (bool, error) {
+	return obj.EndTime == nil, nil
+}
+
+// newPeriod checks a period the way the query of the REST route is checked.
+func newPeriod(start, end time.Time) (period, error) {
+	if !end.After(start) {
+		return period{}, &apperrors.BadRequestError{Message: "end must be after start"}
+	}
+	return period{start: start.UTC(), end: end.UTC()}, nil
+}
+
+func pageSize(first *int) int {
+	if first == nil {
+		return defaultUserPageSize
+	}
+	return *first
+}
@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"time"
+
+	pb "time-tracker/api/timetracker/v1"
+	"time-tracker/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func userToProto(user *models.User) *pb.User {
+	return &pb.User{
+		Id:             int64(user.ID),
+		PassportNumber: user.PassportNumber,
+		Surname:        user.Surname,
+		Name:           user.Name,
+		Patronymic:     user.Patronymic,
+		Address:        user.Address,
+		CreateTime:     timestamppb.New(user.CreatedAt),
+		UpdateTime:     timestamppb.New(user.UpdatedAt),
+		DeleteTime:     optionalTimestamp(user.DeletedAt),
+		Version:        int64(user.Version),
+	}
+}
+
+// userFromProto takes the writable fields of a user.
+func userFromProto(user *pb.User) models.User {
+	return models.User{
+		ID:             int(user.GetId()),
+		PassportNumber: user.GetPassportNumber(),
+		Surname:        user.GetSurname(),
+		Name:           user.GetName(),
+		Patronymic:     user.GetPatronymic(),
+		Address:        user.GetAddress(),
+	}
+}
+
+func taskToProto(task *models.Task) *pb.Task {
+	return &pb.Task{
+		Id:          int64(task.ID),
+		UserId:      int64(task.UserID),
+		Description: task.Description,
+		StartTime:   timestamppb.New(task.StartTime),
+		EndTime:     optionalTimestamp(task.EndTime),
+		CreateTime:  timestamppb.New(task.CreatedAt),
+		UpdateTime:  timestamppb.New(task.UpdatedAt),
+		DeleteTime:  optionalTimestamp(task.DeletedAt),
+		Version:     int64(task.Version),
+	}
+}
+
+func timersToProto(timers []models.Timer) *pb.Timers {
+	out := &pb.Timers{}
+	for _, timer := range timers {
+		out.Timers = append(out.Timers, &pb.Timer{
+			TaskId:         int64(timer.TaskID),
+			UserId:         int64(timer.UserID),
+			Description:    timer.Description,
+			StartTime:      timestamppb.New(timer.StartTime),
+			ElapsedSeconds: timer.ElapsedSeconds,
+		})
+	}
+	return out
+}
+
+// watchResponse converts a message of the live timer stream.
+func watchResponse(msg models.StreamMessage) *pb.WatchTasksResponse {
+	resp := &pb.WatchTasksResponse{EventId: msg.EventID, Time: timestamppb.New(msg.At)}
+	switch msg.Type {
+	case models.StreamSnapshot:
+		resp.Message = &pb.WatchTasksResponse_Snapshot{Snapshot: timersToProto(msg.Timers)}
+	case models.StreamTick:
+		resp.Message = &pb.WatchTasksResponse_Tick{Tick: timersToProto(msg.Timers)}
+	case models.EventTaskStarted:
+		resp.Message = &pb.WatchTasksResponse_TaskStarted{TaskStarted: taskToProto(msg.Task)}
+	case models.EventTaskEnded:
+		resp.Message = &pb.WatchTasksResponse_TaskEnded{TaskEnded: taskToProto(msg.Task)}
+	case models.EventUserDeleted:
+		resp.Message = &pb.WatchTasksResponse_UserDeleted{UserDeleted: int64(msg.UserID)}
+	}
+	return resp
+}
+
+// precondition turns an expected version into the precondition of a write.
+func precondition(version *int64) models.Precondition {
+	if version == nil {
+		return models.Precondition{}
+	}
+	return models.Precondition{Versions: []int{int(*version)}}
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,99 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"time-tracker/internal/logger"
+	"time-tracker/internal/problem"
+	"time-tracker/internal/requestid"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain is the domain of the ErrorInfo details.
+const errorDomain = "time-tracker"
+
+// toStatus maps an error the way problem.Middleware does for REST. The
+// problem type becomes the reason of an ErrorInfo detail and the invalid
+// fields of a validation error become BadRequest field violations.
+func toStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "The request was cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "The request deadline was exceeded")
+	}
+
+	p := problem.FromError(err)
+	if p.Status >= http.StatusInternalServerError {
+		logger.FromContext(ctx).Error("Request failed",
+			"status", p.Status,
+			"error", err,
+		)
+	}
+
+	reason := strings.TrimPrefix(p.Type, "/problems/")
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: map[string]string{"requestId": requestid.FromContext(ctx)},
+	}}
+	if len(p.Errors) > 0 {
+		violations := &errdetails.BadRequest{}
+		for _, fe := range p.Errors {
+			violations.FieldViolations = append(violations.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+			})
+		}
+		details = append(details, violations)
+	}
+
+	st := status.New(code(p.Status, reason), p.Detail)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// code translates the HTTP status of a problem. Conflicts are told apart by
+// the problem type.
+func code(httpStatus int, reason string) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		switch reason {
+		case "duplicate":
+			return codes.AlreadyExists
+		case "task-already-ended":
+			return codes.FailedPrecondition
+		}
+		return codes.Aborted
+	case http.StatusPreconditionFailed, http.StatusPreconditionRequired:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
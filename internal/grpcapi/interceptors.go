@@ -0,0 +1,109 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"time-tracker/internal/auth"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/requestid"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey is the metadata key of the request ID, the counterpart of
+// the X-Request-ID header.
+const requestIDKey = "x-request-id"
+
+func (s *Server) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	err = s.call(ctx, info.FullMethod, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (s *Server) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return s.call(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// call runs an RPC the way the middleware chain of the REST API runs a
+// request: it propagates the request ID, authenticates the bearer token,
+// recovers from panics, maps errors to statuses and writes an access log
+// line.
+func (s *Server) call(ctx context.Context, method string, handler func(ctx context.Context) error) (err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx, id := requestid.NewContext(ctx, first(md, requestIDKey))
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	ctx = logger.WithRoute(ctx, method)
+
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.FromContext(ctx).Error("Panic while serving request",
+				"panic", fmt.Sprint(recovered),
+			)
+			err = status.Error(codes.Internal, "An unexpected error occurred")
+		}
+		logCall(ctx, method, start, err)
+	}()
+
+	principal, ok, err := auth.Authenticate(s.tokens, first(md, "authorization"))
+	if err == nil {
+		if ok {
+			ctx = auth.NewContext(ctx, principal)
+		}
+		err = handler(ctx)
+	}
+	return toStatus(ctx, err)
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	logger.FromContext(ctx).Log(ctx, level, "gRPC request",
+		"method", method,
+		"code", code.String(),
+		"duration", time.Since(start),
+		"peer", addr,
+	)
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// serverStream replaces the context of a stream with the one prepared by
+// call.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
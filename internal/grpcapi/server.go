@@ -0,0 +1,129 @@
+// Package grpcapi serves the gRPC API defined in api/timetracker/v1. It runs
+// on the same repositories as the REST API and shares its authentication,
+// validation and error mapping.
+package grpcapi
+
+//go:generate buf generate --template ../../buf.gen.yaml -o ../.. ../../api
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	pb "time-tracker/api/timetracker/v1"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/config"
+	"time-tracker/internal/health"
+	"time-tracker/internal/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// healthInterval is how often the serving status is refreshed from the
+// readiness checks.
+const healthInterval = 5 * time.Second
+
+type Server struct {
+	cfg     config.GRPCConfig
+	srv     *grpc.Server
+	health  *grpchealth.Server
+	checker *health.Checker
+	tokens  map[string]auth.Principal
+}
+
+// New builds the server. tlsConfig is nil for plaintext.
+func New(cfg config.GRPCConfig, tlsConfig *tls.Config, tokens map[string]auth.Principal, checker *health.Checker, users *UserService, tasks *TaskService) *Server {
+	s := &Server{
+		cfg:     cfg,
+		health:  grpchealth.NewServer(),
+		checker: checker,
+		tokens:  tokens,
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unary),
+		grpc.ChainStreamInterceptor(s.stream),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	s.srv = grpc.NewServer(opts...)
+
+	pb.RegisterUserServiceServer(s.srv, users)
+	pb.RegisterTaskServiceServer(s.srv, tasks)
+	healthpb.RegisterHealthServer(s.srv, s.health)
+	reflection.Register(s.srv)
+	return s
+}
+
+// Run serves until ctx is cancelled, then waits up to the shutdown timeout
+// for in-flight calls before closing the remaining connections.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.FromContext(ctx).Info("gRPC server started",
+			"addr", s.cfg.Addr,
+		)
+		errCh <- s.srv.Serve(ln)
+	}()
+	go s.watchHealth(ctx)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Clients polling the health service stop sending new calls.
+	s.health.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(s.cfg.ShutdownTimeout):
+		logger.FromContext(ctx).Warn("Timed out waiting for gRPC calls, closing connections")
+		s.srv.Stop()
+	}
+	logger.FromContext(ctx).Info("gRPC server stopped")
+	return nil
+}
+
+// watchHealth mirrors the readiness checks in the health service, for the
+// server as a whole and for every service.
+func (s *Server) watchHealth(ctx context.Context) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		ready, _ := s.checker.Run(ctx)
+		status := healthpb.HealthCheckResponse_SERVING
+		if !ready {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		for _, service := range []string{"", pb.UserService_ServiceDesc.ServiceName, pb.TaskService_ServiceDesc.ServiceName} {
+			s.health.SetServingStatus(service, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "time-tracker/api/timetracker/v1"
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/models"
+	"time-tracker/internal/storage"
+	"time-tracker/internal/stream"
+	"time-tracker/internal/validation"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type TaskService struct {
+	pb.UnimplementedTaskServiceServer
+
+	taskRepo storage.TaskRepository
+	hub      *stream.Hub
+	maxUsers int
+}
+
+func NewTaskService(taskRepo storage.TaskRepository, hub *stream.Hub, maxUsers int) *TaskService {
+	return &TaskService{taskRepo: taskRepo, hub: hub, maxUsers: maxUsers}
+}
+
+func (s *TaskService) StartTask(ctx context.Context, req *pb.StartTaskRequest) (*pb.StartTaskResponse, error) {
+	// A negative ID fails the gt=0 rule instead of wrapping around.
+	start := models.Request{UserID: uint(max(req.UserId, 0)), Description: req.Description}
+	if err := validation.Struct(&start, language(ctx)); err != nil {
+		return nil, err
+	}
+
+	task, err := s.taskRepo.StartTask(ctx, int(start.UserID), start.Description)
+	if err != nil {
+		// The user is part of the request, so a missing one is the client's mistake.
+		var noUser *apperrors.NoUserError
+		if errors.As(err, &noUser) {
+			return nil, &apperrors.BadRequestError{Message: noUser.Message}
+		}
+		return nil, err
+	}
+	return &pb.StartTaskResponse{Task: taskToProto(task)}, nil
+}
+
+func (s *TaskService) EndTask(ctx context.Context, req *pb.EndTaskRequest) (*pb.EndTaskResponse, error) {
+	task, err := s.taskRepo.EndTask(ctx, int(req.TaskId), precondition(req.ExpectedVersion))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.EndTaskResponse{Task: taskToProto(task)}, nil
+}
+
+func (s *TaskService) ListTasksByPeriod(ctx context.Context, req *pb.ListTasksByPeriodRequest) (*pb.ListTasksByPeriodResponse, error) {
+	if req.IncludeDeleted && !auth.IsAdmin(ctx) {
+		return nil, &apperrors.ForbiddenError{Message: "Only admins can see deleted records"}
+	}
+
+	// The period goes through the same rules as the query of the REST route.
+	period := models.PeriodQuery{Start: rfc3339(req.StartTime), End: rfc3339(req.EndTime)}
+	if err := validation.Struct(&period, language(ctx)); err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.taskRepo.GetUserTasksByPeriod(ctx, int(req.UserId), req.StartTime.AsTime(), req.EndTime.AsTime(), req.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListTasksByPeriodResponse{}
+	for i := range tasks {
+		resp.Tasks = append(resp.Tasks, taskToProto(&tasks[i]))
+	}
+	return resp, nil
+}
+
+func (s *TaskService) WatchTasks(req *pb.WatchTasksRequest, srv pb.TaskService_WatchTasksServer) error {
+	ctx := srv.Context()
+
+	users := make([]int, 0, len(req.UserIds))
+	for _, id := range req.UserIds {
+		if id <= 0 {
+			return &apperrors.BadRequestError{Message: fmt.Sprintf("Invalid user ID %d", id)}
+		}
+		users = append(users, int(id))
+	}
+	if len(users) > s.maxUsers {
+		return &apperrors.BadRequestError{Message: fmt.Sprintf("At most %d users can be followed", s.maxUsers)}
+	}
+	if len(users) == 0 && !auth.IsAdmin(ctx) {
+		return &apperrors.ForbiddenError{Message: "Only admins can follow every user"}
+	}
+
+	session, err := s.hub.Open(ctx, users)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return session.Run(ctx, func(msg models.StreamMessage) error {
+		return srv.Send(watchResponse(msg))
+	})
+}
+
+func rfc3339(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	return ts.AsTime().Format(time.RFC3339Nano)
+}
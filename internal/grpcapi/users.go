@@ -0,0 +1,165 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	pb "time-tracker/api/timetracker/v1"
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/peopleinfo"
+	"time-tracker/internal/storage"
+	"time-tracker/internal/validation"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+type UserService struct {
+	pb.UnimplementedUserServiceServer
+
+	userRepo   storage.UserRepository
+	peopleInfo *peopleinfo.Client
+	// strict makes expected_version mandatory on updates and deletes, like
+	// If-Match with STRICT_PRECONDITIONS.
+	strict bool
+}
+
+func NewUserService(userRepo storage.UserRepository, peopleInfo *peopleinfo.Client, strictPreconditions bool) *UserService {
+	return &UserService{userRepo: userRepo, peopleInfo: peopleInfo, strict: strictPreconditions}
+}
+
+func (s *UserService) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	if req.IncludeDeleted && !auth.IsAdmin(ctx) {
+		return nil, &apperrors.ForbiddenError{Message: "Only admins can see deleted records"}
+	}
+
+	query := models.UserQuery{
+		Limit:          defaultUserPageSize,
+		Cursor:         req.PageToken,
+		IncludeDeleted: req.IncludeDeleted,
+	}
+	switch {
+	case req.PageSize < 0:
+		return nil, &apperrors.BadRequestError{Message: "Invalid page_size value"}
+	case req.PageSize > 0:
+		query.Limit = min(int(req.PageSize), maxUserPageSize)
+	}
+
+	if req.OrderBy != "" {
+		sort, ok := models.ParseSort(req.OrderBy)
+		if !ok {
+			return nil, &apperrors.BadRequestError{Message: "Invalid order_by value"}
+		}
+		query.Sort = sort
+	}
+
+	for _, filter := range req.Filters {
+		op := models.FilterOp(filter.Op)
+		if op == "" {
+			op = models.OpEq
+		}
+		query.Filters = append(query.Filters, models.FieldFilter{Field: filter.Field, Op: op, Values: filter.Values})
+	}
+
+	page, err := s.userRepo.GetUsers(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListUsersResponse{NextPageToken: page.Next, TotalSize: int32(page.Total)}
+	for i := range page.Users {
+		resp.Users = append(resp.Users, userToProto(&page.Users[i]))
+	}
+	return resp, nil
+}
+
+func (s *UserService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	if req.IncludeDeleted && !auth.IsAdmin(ctx) {
+		return nil, &apperrors.ForbiddenError{Message: "Only admins can see deleted records"}
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, int(req.Id), req.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetUserResponse{User: userToProto(user)}, nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	user := userFromProto(req.User)
+	user.ID = 0
+	if err := validation.Struct(&user, language(ctx)); err != nil {
+		return nil, err
+	}
+
+	// A user carrying only the passport is completed from the People info API.
+	if user.Surname == "" && user.Name == "" && user.Address == "" && s.peopleInfo.Enabled() {
+		passport := strings.Fields(user.PassportNumber)
+		people, err := s.peopleInfo.Lookup(ctx, passport[0], passport[1])
+		if err != nil {
+			return nil, err
+		}
+		user.Surname, user.Name, user.Patronymic, user.Address = people.Surname, people.Name, people.Patronymic, people.Address
+		if err := validation.Struct(&user, language(ctx)); err != nil {
+			logger.FromContext(ctx).Error("People info returned invalid user data",
+				"user", user,
+				"error", err,
+			)
+			return nil, &apperrors.ExternalAPIError{Message: "People info returned invalid user data"}
+		}
+	}
+
+	if err := s.userRepo.CreateUser(ctx, &user); err != nil {
+		return nil, err
+	}
+	return &pb.CreateUserResponse{User: userToProto(&user)}, nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
+	user := userFromProto(req.User)
+	if user.ID <= 0 {
+		return nil, &apperrors.BadRequestError{Message: "Invalid user ID"}
+	}
+	if err := validation.Struct(&user, language(ctx)); err != nil {
+		return nil, err
+	}
+	if err := s.requireVersion(req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateUser(ctx, &user, precondition(req.ExpectedVersion)); err != nil {
+		return nil, err
+	}
+	return &pb.UpdateUserResponse{User: userToProto(&user)}, nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	if err := s.requireVersion(req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.DeleteUser(ctx, int(req.Id), precondition(req.ExpectedVersion)); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteUserResponse{}, nil
+}
+
+func (s *UserService) requireVersion(version *int64) error {
+	if s.strict && version == nil {
+		return &apperrors.PreconditionRequiredError{Message: "expected_version is required"}
+	}
+	return nil
+}
+
+// language is the language validation messages are rendered in, read from
+// the accept-language metadata.
+func language(ctx context.Context) validation.Lang {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return validation.Language(first(md, "accept-language"))
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CheckFunc reports a dependency as unavailable by returning an error.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of one check. Error may name internal hosts and
+// accounts, so public endpoints clear it.
+type Result struct {
+	Name      string    `json:"name"`
+	OK        bool      `json:"ok"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Duration  string    `json:"duration"`
+}
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+
+	mu     sync.Mutex
+	result *Result
+}
+
+// Checker runs readiness checks, caching every result for ttl so that
+// frequent probes don't hammer the database or external APIs.
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+	checks  []*check
+}
+
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout}
+}
+
+// Add registers a check. Failing non-critical checks are reported but don't
+// make the service unready.
+func (c *Checker) Add(name string, critical bool, fn CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, critical: critical, fn: fn})
+}
+
+// Run returns the result of every check, running the stale ones concurrently.
+func (c *Checker) Run(ctx context.Context) (ready bool, results []Result) {
+	results = make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func(i int, ch *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	ready = true
+	for _, r := range results {
+		if r.Critical && !r.OK {
+			ready = false
+		}
+	}
+	return ready, results
+}
+
+func (c *Checker) run(ctx context.Context, ch *check) Result {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.result != nil && time.Since(ch.result.CheckedAt) < c.ttl {
+		return *ch.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ch.fn(ctx)
+	result := &Result{
+		Name:      ch.name,
+		OK:        err == nil,
+		Critical:  ch.critical,
+		CheckedAt: start,
+		Duration:  time.Since(start).String(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	ch.result = result
+	return *result
+}
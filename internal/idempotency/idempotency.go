@@ -0,0 +1,147 @@
+// Package idempotency lets clients retry unsafe requests with an
+// Idempotency-Key header without repeating their side effects.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/config"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	Header = "Idempotency-Key"
+	// ReplayedHeader marks responses served from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	anonymous    = "anonymous"
+)
+
+// storedHeaders are recorded with the response and sent again on replay.
+var storedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Middleware answers a retried request with the response recorded for its
+// Idempotency-Key. Keys are scoped to the caller, or to the client IP for
+// anonymous callers; reusing one for a different method, path or body fails
+// with 422, and a retry arriving while the first request is still running gets
+// 409. Only successful responses are recorded: on an error the key is released
+// so that the client can retry.
+//
+// It must run after auth.Middleware.
+func Middleware(repo *db.IdempotencyRepository, cfg config.IdempotencyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.Error(&apperrors.BadRequestError{Message: "Idempotency-Key must be at most " + strconv.Itoa(maxKeyLength) + " characters long"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(&apperrors.BadRequestError{Message: "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Anonymous callers are told apart by IP like the rate limiter does,
+		// so that they cannot replay each other's responses.
+		scope := anonymous + ":" + c.ClientIP()
+		if principal, ok := auth.FromContext(c.Request.Context()); ok {
+			scope = principal.Subject
+		}
+		hash := requestHash(c.Request, body)
+
+		stored, err := repo.Reserve(c, scope, key, hash, cfg.TTL, cfg.LockTimeout)
+		if err != nil {
+			var inProgress *apperrors.RequestInProgressError
+			if errors.As(err, &inProgress) {
+				c.Header("Retry-After", "1")
+			}
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			replay(c, stored)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		completed := false
+		// The outcome is recorded even if the client has gone away; the
+		// deferred release also runs when the handler panics.
+		ctx := context.WithoutCancel(c.Request.Context())
+		defer func() {
+			if !completed {
+				repo.Release(ctx, scope, key)
+			}
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if len(c.Errors) > 0 || status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+		resp := &models.StoredResponse{Status: status, Header: make(map[string]string), Body: rec.body.Bytes()}
+		for _, name := range storedHeaders {
+			if val := c.Writer.Header().Get(name); val != "" {
+				resp.Header[name] = val
+			}
+		}
+		completed = repo.Complete(ctx, scope, key, resp) == nil
+	}
+}
+
+func replay(c *gin.Context, stored *models.StoredResponse) {
+	for name, val := range stored.Header {
+		c.Header(name, val)
+	}
+	c.Header(ReplayedHeader, "true")
+	c.Data(stored.Status, stored.Header["Content-Type"], stored.Body)
+	c.Abort()
+}
+
+// requestHash identifies the request a key was first used with.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the response body.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
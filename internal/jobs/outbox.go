@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"time-tracker/internal/config"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+	"time-tracker/internal/outbox"
+)
+
+// Relay publishes the events of the transactional outbox to the sinks with
+// at-least-once semantics and removes published events after the retention
+// period.
+type Relay struct {
+	repo  *db.OutboxRepository
+	sinks []outbox.Sink
+	cfg   config.OutboxConfig
+
+	mu     sync.Mutex
+	status RelayStatus
+}
+
+// RelayStatus describes the relay for /debug/status.
+type RelayStatus struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	Interval  string     `json:"interval"`
+	Sinks     []string   `json:"sinks"`
+	LastRun   *time.Time `json:"lastRun,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+	Published int64      `json:"published"`
+	Purged    int64      `json:"purged"`
+}
+
+func NewRelay(repo *db.OutboxRepository, sinks []outbox.Sink, cfg config.OutboxConfig) *Relay {
+	names := make([]string, 0, len(sinks))
+	for _, sink := range sinks {
+		names = append(names, sink.Name())
+	}
+	return &Relay{
+		repo:   repo,
+		sinks:  sinks,
+		cfg:    cfg,
+		status: RelayStatus{Name: "outbox", Interval: cfg.PollInterval.String(), Sinks: names},
+	}
+}
+
+func (r *Relay) Status() RelayStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+func (r *Relay) update(fn func(s *RelayStatus)) {
+	r.mu.Lock()
+	fn(&r.status)
+	r.mu.Unlock()
+}
+
+// Run relays pending events on every tick until ctx is cancelled. Published
+// events are purged once an hour.
+func (r *Relay) Run(ctx context.Context) {
+	logger.FromContext(ctx).Info("Outbox relay started",
+		"interval", r.cfg.PollInterval,
+		"sinks", r.status.Sinks,
+	)
+
+	r.update(func(s *RelayStatus) { s.Running = true })
+	defer r.update(func(s *RelayStatus) { s.Running = false })
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+	for {
+		if time.Since(lastPurge) >= time.Hour {
+			r.purge(ctx)
+			lastPurge = time.Now()
+		}
+		r.relay(ctx)
+
+		select {
+		case <-ctx.Done():
+			logger.FromContext(ctx).Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay publishes batches until the outbox is drained or publishing fails.
+func (r *Relay) relay(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.repo.Relay(ctx, r.cfg.BatchSize, r.publish(ctx))
+
+		now := time.Now()
+		r.update(func(s *RelayStatus) {
+			s.LastRun = &now
+			s.Published += int64(published)
+			s.LastError = ""
+			if err != nil {
+				s.LastError = err.Error()
+			}
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.FromContext(ctx).Warn("Outbox relay failed, retrying on the next tick",
+					"published", published,
+					"error", err,
+				)
+			}
+			return
+		}
+		if published < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// publish hands an event to every sink. A sink that fails makes the event
+// go to all sinks again on retry.
+func (r *Relay) publish(ctx context.Context) func(event models.OutboxEvent) error {
+	return func(event models.OutboxEvent) error {
+		for _, sink := range r.sinks {
+			if err := sink.Publish(ctx, event); err != nil {
+				return fmt.Errorf("%s sink: %w", sink.Name(), err)
+			}
+		}
+		return nil
+	}
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	n, err := r.repo.PurgePublished(ctx, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		return
+	}
+	r.update(func(s *RelayStatus) { s.Purged += n })
+}
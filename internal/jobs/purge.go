@@ -0,0 +1,115 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+)
+
+// Purger periodically hard-deletes rows that stayed soft-deleted longer than
+// the retention period, along with expired idempotency keys and refilled
+// rate limit buckets.
+type Purger struct {
+	userRepo        *db.UserRepository
+	idempotencyRepo *db.IdempotencyRepository
+	rateLimitRepo   *db.RateLimitRepository
+	retention       time.Duration
+	interval        time.Duration
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Status describes the job for /debug/status.
+type Status struct {
+	Name          string     `json:"name"`
+	Running       bool       `json:"running"`
+	Interval      string     `json:"interval"`
+	Runs          int        `json:"runs"`
+	LastRun       *time.Time `json:"lastRun,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	UsersPurged   int64      `json:"usersPurged"`
+	TasksPurged   int64      `json:"tasksPurged"`
+	KeysPurged    int64      `json:"idempotencyKeysPurged"`
+	BucketsPurged int64      `json:"rateLimitBucketsPurged"`
+}
+
+func NewPurger(userRepo *db.UserRepository, idempotencyRepo *db.IdempotencyRepository, rateLimitRepo *db.RateLimitRepository, retention, interval time.Duration) *Purger {
+	return &Purger{
+		userRepo:        userRepo,
+		idempotencyRepo: idempotencyRepo,
+		rateLimitRepo:   rateLimitRepo,
+		retention:       retention,
+		interval:        interval,
+		status:          Status{Name: "purge", Interval: interval.String()},
+	}
+}
+
+func (p *Purger) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *Purger) setRunning(running bool) {
+	p.mu.Lock()
+	p.status.Running = running
+	p.mu.Unlock()
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	users, tasks, err := p.userRepo.PurgeDeleted(ctx, time.Now().Add(-p.retention))
+	var keys, buckets int64
+	if err == nil {
+		keys, err = p.idempotencyRepo.PurgeExpired(ctx, time.Now())
+	}
+	if err == nil {
+		buckets, err = p.rateLimitRepo.PurgeExpired(ctx, time.Now())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Runs++
+	now := time.Now()
+	p.status.LastRun = &now
+	p.status.LastError = ""
+	if err != nil {
+		p.status.LastError = err.Error()
+		logger.FromContext(ctx).Error("Purge job run failed",
+			"error", err,
+		)
+		return
+	}
+	p.status.UsersPurged += users
+	p.status.TasksPurged += tasks
+	p.status.KeysPurged += keys
+	p.status.BucketsPurged += buckets
+}
+
+// Run purges once immediately and then on every tick until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	logger.FromContext(ctx).Info("Purge job started",
+		"retention", p.retention,
+		"interval", p.interval,
+	)
+
+	p.setRunning(true)
+	defer p.setRunning(false)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			logger.FromContext(ctx).Info("Purge job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"time-tracker/internal/config"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Headers of a webhook request. The signature is the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+const (
+	HeaderWebhookID        = "X-Webhook-ID"
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+// Dispatcher sends the queued webhook deliveries, retrying failed ones with
+// exponential backoff.
+type Dispatcher struct {
+	repo *db.WebhookRepository
+	cfg  config.WebhookConfig
+	http *http.Client
+
+	mu     sync.Mutex
+	status WebhookStatus
+}
+
+// WebhookStatus describes the dispatcher for /debug/status.
+type WebhookStatus struct {
+	Name       string     `json:"name"`
+	Running    bool       `json:"running"`
+	Interval   string     `json:"interval"`
+	LastRun    *time.Time `json:"lastRun,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+	Delivered  int64      `json:"delivered"`
+	Failed     int64      `json:"failed"`
+	GaveUp     int64      `json:"gaveUp"`
+	LogsPurged int64      `json:"logsPurged"`
+}
+
+func NewDispatcher(repo *db.WebhookRepository, cfg config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+		cfg:  cfg,
+		http: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			// Redirects are not followed so that a receiver can't bounce
+			// signed payloads elsewhere.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		status: WebhookStatus{Name: "webhooks", Interval: cfg.PollInterval.String()},
+	}
+}
+
+func (d *Dispatcher) Status() WebhookStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+func (d *Dispatcher) update(fn func(s *WebhookStatus)) {
+	d.mu.Lock()
+	fn(&d.status)
+	d.mu.Unlock()
+}
+
+// Run delivers due webhooks on every tick until ctx is cancelled. The
+// delivery log is trimmed once an hour.
+func (d *Dispatcher) Run(ctx context.Context) {
+	logger.FromContext(ctx).Info("Webhook dispatcher started",
+		"interval", d.cfg.PollInterval,
+	)
+
+	d.update(func(s *WebhookStatus) { s.Running = true })
+	defer d.update(func(s *WebhookStatus) { s.Running = false })
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+	for {
+		if time.Since(lastPurge) >= time.Hour {
+			d.purge(ctx)
+			lastPurge = time.Now()
+		}
+		d.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			logger.FromContext(ctx).Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch sends due deliveries until none are left or ctx is cancelled.
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		// The lease outlasts every request of the batch.
+		lease := time.Duration(d.cfg.BatchSize+1) * d.cfg.Timeout
+		due, err := d.repo.ClaimDue(ctx, time.Now(), d.cfg.BatchSize, lease)
+
+		now := time.Now()
+		d.update(func(s *WebhookStatus) {
+			s.LastRun = &now
+			s.LastError = ""
+			if err != nil {
+				s.LastError = err.Error()
+			}
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.FromContext(ctx).Error("Failed to claim webhook deliveries",
+					"error", err,
+				)
+			}
+			return
+		}
+
+		for _, w := range due {
+			d.deliver(ctx, w)
+		}
+		if len(due) < d.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, w models.OutgoingWebhook) {
+	attempt := d.send(ctx, w)
+	if !attempt.Delivered && w.Attempts+1 < d.cfg.MaxAttempts {
+		next := time.Now().Add(d.backoff(w.Attempts + 1))
+		attempt.NextAttemptAt = &next
+	}
+
+	// The result is stored even when shutdown interrupted the request so
+	// that the delivery is retried.
+	if err := d.repo.RecordAttempt(context.WithoutCancel(ctx), w.DeliveryID, attempt); err != nil {
+		logger.FromContext(ctx).Error("Failed to record webhook delivery attempt",
+			"deliveryID", w.DeliveryID,
+			"error", err,
+		)
+	}
+
+	d.update(func(s *WebhookStatus) {
+		switch {
+		case attempt.Delivered:
+			s.Delivered++
+		case attempt.NextAttemptAt == nil:
+			s.GaveUp++
+		default:
+			s.Failed++
+		}
+	})
+	if !attempt.Delivered {
+		logger.FromContext(ctx).Warn("Webhook delivery failed",
+			"deliveryID", w.DeliveryID,
+			"event", w.EventType,
+			"url", w.URL,
+			"attempt", w.Attempts+1,
+			"status", attempt.StatusCode,
+			"error", attempt.Error,
+			"willRetry", attempt.NextAttemptAt != nil,
+		)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, w models.OutgoingWebhook) models.WebhookAttempt {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(w.Payload))
+	if err != nil {
+		return models.WebhookAttempt{Error: err.Error()}
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "time-tracker-webhooks")
+	req.Header.Set(HeaderWebhookID, w.EventID)
+	req.Header.Set(HeaderWebhookDelivery, strconv.FormatInt(w.DeliveryID, 10))
+	req.Header.Set(HeaderWebhookEvent, w.EventType)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, "sha256="+Sign(w.Secret, timestamp, w.Payload))
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return models.WebhookAttempt{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt := models.WebhookAttempt{StatusCode: resp.StatusCode}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		attempt.Delivered = true
+	} else {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// backoff doubles the delay with every attempt and adds up to 20% jitter so
+// that retries of a burst of failures spread out.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.RetryMax
+	if attempts < 32 {
+		if exp := d.cfg.RetryBase << (attempts - 1); exp > 0 && exp < delay {
+			delay = exp
+		}
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+func (d *Dispatcher) purge(ctx context.Context) {
+	n, err := d.repo.PurgeDeliveries(ctx, time.Now().Add(-d.cfg.LogRetention))
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to purge the webhook delivery log",
+			"error", err,
+		)
+		return
+	}
+	d.update(func(s *WebhookStatus) { s.LogsPurged += n })
+}
+
+// Sign computes the signature of a webhook body sent at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
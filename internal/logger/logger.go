@@ -1,24 +1,96 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/sirupsen/logrus"
-)
+	"time-tracker/internal/auth"
+	"time-tracker/internal/config"
+	"time-tracker/internal/requestid"
 
-var Logger = logrus.New()
+	"gopkg.in/natefinch/lumberjack.v2"
+)
 
-func init() {
+// Logger is the process-wide logger. Until Configure is called it writes
+// JSON at debug level to stderr.
+var Logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
-	Logger.SetLevel(logrus.DebugLevel)
+// Configure applies the logging settings and makes the result the slog
+// default as well. An unusable log file is an error rather than a silent
+// fallback to stderr.
+func Configure(cfg config.LogConfig) error {
+	var level slog.Level
+	switch strings.ToLower(cfg.Level) {
+	case "warning":
+		level = slog.LevelWarn
+	default:
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return err
+		}
+	}
 
-	Logger.SetFormatter(&logrus.JSONFormatter{})
+	var out io.Writer = os.Stderr
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			return fmt.Errorf("create log directory: %w", err)
+		}
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("open log file: %w", err)
+		}
+		file.Close()
+		out = &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+		}
+	}
 
-	file, err := os.OpenFile("/var/log/myapp/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err == nil {
-		Logger.SetOutput(file)
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if strings.EqualFold(cfg.Format, "text") {
+		handler = slog.NewTextHandler(out, opts)
 	} else {
-		Logger.Info("Failed to open log file")
+		handler = slog.NewJSONHandler(out, opts)
+	}
+	if cfg.SampleInitial > 0 {
+		handler = newSampler(handler, cfg.SampleInitial, cfg.SampleThereafter)
+	}
+
+	Logger = slog.New(handler)
+	slog.SetDefault(Logger)
+	Logger.Debug("Logger configured", "level", level.String(), "format", cfg.Format, "file", cfg.File)
+	return nil
+}
+
+type routeKey struct{}
+
+// WithRoute stores the matched route template for FromContext.
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// FromContext returns Logger annotated with the request ID, the
+// authenticated subject and the route carried by ctx, if any.
+func FromContext(ctx context.Context) *slog.Logger {
+	l := Logger
+	if ctx == nil {
+		return l
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		l = l.With("request_id", id)
+	}
+	if principal, ok := auth.FromContext(ctx); ok {
+		l = l.With("user", principal.Subject)
+	}
+	if route, ok := ctx.Value(routeKey{}).(string); ok {
+		l = l.With("route", route)
 	}
-	Logger.Debug("init() function completed")
+	return l
 }
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware puts the route template into the request context for
+// FromContext and writes one access log line per request. It must run after
+// requestid.Middleware.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		if route := c.FullPath(); route != "" {
+			c.Request = c.Request.WithContext(WithRoute(c.Request.Context(), route))
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		FromContext(c.Request.Context()).Log(c.Request.Context(), level, "HTTP request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration", time.Since(start),
+			"size", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
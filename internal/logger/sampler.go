@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sampler throttles debug records: within every second the first `initial`
+// records with a given message pass, then only every `thereafter`-th one.
+// Info and above are never dropped.
+type sampler struct {
+	next       slog.Handler
+	initial    int
+	thereafter int
+	state      *samplerState
+}
+
+type samplerState struct {
+	mu     sync.Mutex
+	window time.Time
+	counts map[string]int
+}
+
+func newSampler(next slog.Handler, initial, thereafter int) *sampler {
+	return &sampler{
+		next:       next,
+		initial:    initial,
+		thereafter: thereafter,
+		state:      &samplerState{counts: make(map[string]int)},
+	}
+}
+
+func (s *sampler) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.next.Enabled(ctx, level)
+}
+
+func (s *sampler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelInfo || s.allow(r.Time, r.Message) {
+		return s.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (s *sampler) allow(now time.Time, msg string) bool {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if window := now.Truncate(time.Second); !window.Equal(s.state.window) {
+		s.state.window = window
+		s.state.counts = make(map[string]int)
+	}
+	s.state.counts[msg]++
+	n := s.state.counts[msg]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
+
+func (s *sampler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampler{next: s.next.WithAttrs(attrs), initial: s.initial, thereafter: s.thereafter, state: s.state}
+}
+
+func (s *sampler) WithGroup(name string) slog.Handler {
+	return &sampler{next: s.next.WithGroup(name), initial: s.initial, thereafter: s.thereafter, state: s.state}
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"time-tracker/internal/logger"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exports pgxpool.Stat on every scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	idleConns            *prometheus.Desc
+	maxConns             *prometheus.Desc
+	totalConns           *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+	maxLifetimeDestroys  *prometheus.Desc
+	maxIdleDestroys      *prometheus.Desc
+}
+
+func RegisterPool(pool *pgxpool.Pool) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	prometheus.MustRegister(&poolCollector{
+		pool:                 pool,
+		acquireCount:         desc("acquires_total", "Successful connection acquires."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		acquiredConns:        desc("acquired_connections", "Connections currently in use."),
+		canceledAcquireCount: desc("canceled_acquires_total", "Acquires cancelled by their context."),
+		constructingConns:    desc("constructing_connections", "Connections being established."),
+		emptyAcquireCount:    desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		idleConns:            desc("idle_connections", "Idle connections."),
+		maxConns:             desc("max_connections", "Maximum pool size."),
+		totalConns:           desc("total_connections", "Open connections."),
+		newConnsCount:        desc("new_connections_total", "Connections opened."),
+		maxLifetimeDestroys:  desc("max_lifetime_destroys_total", "Connections closed because of MaxConnLifetime."),
+		maxIdleDestroys:      desc("max_idle_destroys_total", "Connections closed because of MaxConnIdleTime."),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter(c.acquireCount, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	counter(c.canceledAcquireCount, float64(stat.CanceledAcquireCount()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	counter(c.emptyAcquireCount, float64(stat.EmptyAcquireCount()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	counter(c.newConnsCount, float64(stat.NewConnsCount()))
+	counter(c.maxLifetimeDestroys, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroys, float64(stat.MaxIdleDestroyCount()))
+}
+
+// RegisterRunningTasks exports the number of started but not yet ended
+// tasks, queried on every scrape.
+func RegisterRunningTasks(count func(ctx context.Context) (int, error)) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tasks_running",
+		Help:      "Tasks that are started and not yet ended.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		n, err := count(ctx)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to count running tasks",
+				"error", err,
+			)
+			return math.NaN()
+		}
+		return float64(n)
+	}))
+}
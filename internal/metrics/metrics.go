@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "time_tracker"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	peopleInfoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "people_info_request_duration_seconds",
+		Help:      "People info API call latency by outcome.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2, 5},
+	}, []string{"outcome"})
+
+	peopleInfoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "people_info_errors_total",
+		Help:      "Failed People info API calls by reason.",
+	}, []string{"reason"})
+
+	tasksStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_started_total",
+		Help:      "Tasks started.",
+	})
+
+	tasksEnded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_ended_total",
+		Help:      "Tasks ended.",
+	})
+
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by the rate limiter by route group.",
+	}, []string{"group"})
+
+	streamClients = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stream_clients",
+		Help:      "Connected live timer clients by transport.",
+	}, []string{"transport"})
+)
+
+// Middleware records request count and latency. Requests are labelled with
+// the route template, e.g. /api/users/:userID, and unmatched ones with
+// "unmatched" so that arbitrary paths can't blow up the label cardinality.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		httpInFlight.Inc()
+		start := time.Now()
+
+		c.Next()
+
+		httpInFlight.Dec()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObservePeopleInfo records one People info API call. reason is empty on
+// success and a short fixed string such as "transport" or "status" otherwise.
+func ObservePeopleInfo(start time.Time, reason string) {
+	outcome := "success"
+	if reason != "" {
+		outcome = "error"
+		peopleInfoErrors.WithLabelValues(reason).Inc()
+	}
+	peopleInfoDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
+
+func TaskStarted() {
+	tasksStarted.Inc()
+}
+
+func TaskEnded() {
+	tasksEnded.Inc()
+}
+
+func RateLimited(group string) {
+	rateLimited.WithLabelValues(group).Inc()
+}
+
+// StreamOpened counts a live timer client in until StreamClosed is called.
+func StreamOpened(transport string) {
+	streamClients.WithLabelValues(transport).Inc()
+}
+
+func StreamClosed(transport string) {
+	streamClients.WithLabelValues(transport).Dec()
+}
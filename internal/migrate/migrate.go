@@ -0,0 +1,401 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"time-tracker/internal/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockKey identifies the advisory lock held while migrating, so that several
+// replicas starting at once apply every migration exactly once.
+const lockKey int64 = 0x74696d6574726b // "timetrk"
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// destructive matches statements that lose data when a migration is rolled back.
+var destructive = regexp.MustCompile(`(?i)\b(DROP\s+(TABLE|COLUMN|SCHEMA)|TRUNCATE|DELETE\s+FROM)\b`)
+
+// Migration is a pair of up and down scripts sharing a version.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Destructive reports whether rolling the migration back drops data.
+func (m Migration) Destructive() bool {
+	return destructive.MatchString(m.Down)
+}
+
+// Applied is a row of the schema_versions table.
+type Applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// State pairs a known migration with its applied record, if any.
+type State struct {
+	Migration
+	Applied  *Applied
+	Modified bool
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+// New loads the *.up.sql / *.down.sql pairs from fsys.
+func New(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	scripts := make(map[string]string)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		// 1_x.up.sql and 0001_x.up.sql are the same script twice.
+		script := strconv.FormatInt(version, 10) + "." + match[3]
+		if other, ok := scripts[script]; ok {
+			return nil, fmt.Errorf("migration %d has two %s scripts: %s and %s", version, match[3], other, entry.Name())
+		}
+		scripts[script] = entry.Name()
+		data, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+			sum := sha256.Sum256(data)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{pool: pool, migrations: migrations}, nil
+}
+
+// Latest is the version the embedded migrations lead to.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest(), false)
+}
+
+// Down rolls back the given number of applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int, allowDestructive bool) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := appliedVersions(applied)
+		target := int64(0)
+		if steps < len(versions) {
+			target = versions[len(versions)-steps-1]
+		}
+		return m.migrate(ctx, conn, applied, target, allowDestructive)
+	})
+}
+
+// To migrates up or down until version is the latest applied one.
+func (m *Migrator) To(ctx context.Context, version int64, allowDestructive bool) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, applied, version, allowDestructive)
+	})
+}
+
+// Status lists every known migration together with its applied record.
+// Applied versions missing from the binary are reported as an error.
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	applied := map[int64]Applied{}
+	var exists bool
+	if err := conn.QueryRow(ctx, `SELECT to_regclass('schema_versions') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		if applied, err = m.applied(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	states := make([]State, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		state := State{Migration: mig}
+		if a, ok := applied[mig.Version]; ok {
+			a := a
+			state.Applied = &a
+			state.Modified = a.Checksum != mig.Checksum
+		}
+		states = append(states, state)
+	}
+	for version := range applied {
+		if m.find(version) == nil {
+			return states, fmt.Errorf("applied migration %d is unknown to this build", version)
+		}
+	}
+	return states, nil
+}
+
+// Check verifies that the schema is at the latest version and that no
+// applied migration has been edited since.
+func (m *Migrator) Check(ctx context.Context) error {
+	states, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		if state.Applied == nil {
+			return fmt.Errorf("migration %d (%s) is pending", state.Version, state.Name)
+		}
+		if state.Modified {
+			return fmt.Errorf("migration %d (%s) was modified after it was applied", state.Version, state.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// The session lock must be released even if ctx was cancelled.
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			logger.FromContext(ctx).Error("Failed to release migration lock",
+				"error", err,
+			)
+		}
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error { return m.adoptLegacy(ctx, tx) }); err != nil {
+		return fmt.Errorf("adopt schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func ensureTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_versions (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	return err
+}
+
+// adoptLegacy takes over a schema previously managed by golang-migrate: the
+// version recorded in schema_migrations and all before it count as applied.
+func (m *Migrator) adoptLegacy(ctx context.Context, tx pgx.Tx) error {
+	var exists, empty bool
+	err := tx.QueryRow(ctx, `
+		SELECT to_regclass('schema_migrations') IS NOT NULL,
+		       NOT EXISTS (SELECT 1 FROM schema_versions)`).Scan(&exists, &empty)
+	if err != nil || !exists || !empty {
+		return err
+	}
+	var version int64
+	var dirty bool
+	err = tx.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("legacy schema_migrations is dirty at version %d, fix it manually", version)
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_versions (version, name, checksum) VALUES ($1, $2, $3)`, mig.Version, mig.Name, mig.Checksum); err != nil {
+			return err
+		}
+	}
+	logger.FromContext(ctx).Info("Adopted schema version from schema_migrations",
+		"version", version,
+	)
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int64]Applied, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_versions`)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]Applied)
+	for rows.Next() {
+		var a Applied
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		applied[a.Version] = a
+	}
+	rows.Close()
+	return applied, rows.Err()
+}
+
+func appliedVersions(applied map[int64]Applied) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *pgxpool.Conn, applied map[int64]Applied, target int64, allowDestructive bool) error {
+	// Refuse to build on top of edited history.
+	for _, mig := range m.migrations {
+		if a, ok := applied[mig.Version]; ok && a.Checksum != mig.Checksum {
+			return fmt.Errorf("checksum mismatch for migration %d (%s): it was modified after being applied", mig.Version, mig.Name)
+		}
+	}
+	for version := range applied {
+		if m.find(version) == nil {
+			return fmt.Errorf("applied migration %d is unknown to this build", version)
+		}
+	}
+
+	var down []Migration
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; ok && mig.Version > target {
+			down = append(down, mig)
+		}
+	}
+	if !allowDestructive {
+		for _, mig := range down {
+			if mig.Destructive() {
+				return fmt.Errorf("rolling back migration %d (%s) destroys data; pass --allow-destructive to proceed", mig.Version, mig.Name)
+			}
+		}
+	}
+	for _, mig := range down {
+		if strings.TrimSpace(mig.Down) == "" {
+			return fmt.Errorf("migration %d (%s) has no down script", mig.Version, mig.Name)
+		}
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, mig.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `DELETE FROM schema_versions WHERE version = $1`, mig.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("roll back migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+		logger.FromContext(ctx).Info("Migration rolled back",
+			"version", mig.Version,
+			"name", mig.Name,
+		)
+	}
+
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok || mig.Version > target {
+			continue
+		}
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, mig.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_versions (version, name, checksum) VALUES ($1, $2, $3)`, mig.Version, mig.Name, mig.Checksum)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("apply migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+		logger.FromContext(ctx).Info("Migration applied",
+			"version", mig.Version,
+			"name", mig.Name,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"time-tracker/migrations"
+)
+
+func TestNew(t *testing.T) {
+	file := func(data string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(data)} }
+
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int64
+		wantErr  string
+	}{
+		{
+			name: "pairs sorted by version",
+			fsys: fstest.MapFS{
+				"0000002_b.up.sql":   file("CREATE TABLE b ();"),
+				"0000002_b.down.sql": file("DROP TABLE b;"),
+				"0000001_a.up.sql":   file("CREATE TABLE a ();"),
+				"0000001_a.down.sql": file("DROP TABLE a;"),
+			},
+			versions: []int64{1, 2},
+		},
+		{
+			name: "missing up file",
+			fsys: fstest.MapFS{
+				"0000001_a.up.sql":   file("CREATE TABLE a ();"),
+				"0000002_b.down.sql": file("DROP TABLE b;"),
+			},
+			wantErr: "migration 2 has no up script",
+		},
+		{
+			name: "bad names are ignored",
+			fsys: fstest.MapFS{
+				"0000001_a.up.sql":   file("CREATE TABLE a ();"),
+				"README.md":          file("# migrations"),
+				"a_b.up.sql":         file("CREATE TABLE b ();"),
+				"0000002_b.sql":      file("CREATE TABLE b ();"),
+				"0000003_c.UP.sql":   file("CREATE TABLE c ();"),
+				"0000004.up.sql":     file("CREATE TABLE d ();"),
+				"0000005_e.up.sql/x": file("a directory, not a script"),
+			},
+			versions: []int64{1},
+		},
+		{
+			name: "version out of range",
+			fsys: fstest.MapFS{
+				"99999999999999999999_a.up.sql": file("CREATE TABLE a ();"),
+			},
+			wantErr: "value out of range",
+		},
+		{
+			name: "duplicate up scripts",
+			fsys: fstest.MapFS{
+				"1_a.up.sql":       file("CREATE TABLE a ();"),
+				"0000001_a.up.sql": file("CREATE TABLE a ();"),
+			},
+			wantErr: "migration 1 has two up scripts",
+		},
+		{
+			name: "duplicate down scripts",
+			fsys: fstest.MapFS{
+				"0000001_a.up.sql":   file("CREATE TABLE a ();"),
+				"0000001_a.down.sql": file("DROP TABLE a;"),
+				"01_a.down.sql":      file("DROP TABLE a;"),
+			},
+			wantErr: "migration 1 has two down scripts",
+		},
+		{
+			name: "names differ within a version",
+			fsys: fstest.MapFS{
+				"0000001_a.up.sql":   file("CREATE TABLE a ();"),
+				"0000001_b.down.sql": file("DROP TABLE a;"),
+			},
+			wantErr: "migration 1 has different names: a and b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(nil, tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			var versions []int64
+			for _, mig := range m.migrations {
+				versions = append(versions, mig.Version)
+				if mig.Checksum == "" {
+					t.Errorf("migration %d has no checksum", mig.Version)
+				}
+			}
+			if len(versions) != len(tt.versions) {
+				t.Fatalf("versions = %v, want %v", versions, tt.versions)
+			}
+			for i := range versions {
+				if versions[i] != tt.versions[i] {
+					t.Fatalf("versions = %v, want %v", versions, tt.versions)
+				}
+			}
+		})
+	}
+}
+
+func TestNewMissingDown(t *testing.T) {
+	m, err := New(nil, fstest.MapFS{
+		"0000001_a.up.sql": &fstest.MapFile{Data: []byte("CREATE TABLE a ();")},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if mig := m.migrations[0]; mig.Down != "" || mig.Destructive() {
+		t.Errorf("migration without down script = %+v, want empty Down, not destructive", mig)
+	}
+}
+
+func TestNewEmbedded(t *testing.T) {
+	m, err := New(nil, migrations.FS)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, mig := range m.migrations {
+		if strings.TrimSpace(mig.Down) == "" {
+			t.Errorf("migration %d (%s) has no down script", mig.Version, mig.Name)
+		}
+	}
+}
+
+func TestDestructive(t *testing.T) {
+	tests := []struct {
+		down string
+		want bool
+	}{
+		{down: "DROP TABLE users;", want: true},
+		{down: "drop table if exists users;", want: true},
+		{down: "ALTER TABLE users DROP COLUMN deleted_at;", want: true},
+		{down: "ALTER TABLE users\n\tDROP   COLUMN version;", want: true},
+		{down: "DROP SCHEMA audit CASCADE;", want: true},
+		{down: "TRUNCATE outbox;", want: true},
+		{down: "DELETE FROM schema_versions;", want: true},
+		{down: "DROP INDEX users_search_idx;", want: false},
+		{down: "ALTER TABLE users DROP CONSTRAINT users_passport_key;", want: false},
+		{down: "DROP TRIGGER users_search ON users;", want: false},
+		{down: "ALTER TABLE users RENAME COLUMN dropped_at TO deleted_at;", want: false},
+		{down: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := (Migration{Down: tt.down}).Destructive(); got != tt.want {
+			t.Errorf("Destructive(%q) = %v, want %v", tt.down, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   int             `json:"entityId"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Diff       json.RawMessage `json:"diff,omitempty"`
+	RequestID  string          `json:"requestId,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// AuditFilter narrows down GET /api/audit. Zero values mean "any".
+type AuditFilter struct {
+	EntityType string
+	EntityID   int
+	Actor      string
+	Action     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
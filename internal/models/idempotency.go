@@ -0,0 +1,9 @@
+package models
+
+// StoredResponse is the response recorded for an Idempotency-Key and
+// replayed when the request is retried.
+type StoredResponse struct {
+	Status int
+	Header map[string]string
+	Body   []byte
+}
@@ -0,0 +1,19 @@
+package models
+
+// Precondition restricts a write to the listed versions of an entity.
+// The zero value matches any version.
+type Precondition struct {
+	Versions []int
+}
+
+func (p Precondition) Matches(version int) bool {
+	if len(p.Versions) == 0 {
+		return true
+	}
+	for _, v := range p.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
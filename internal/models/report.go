@@ -0,0 +1,25 @@
+package models
+
+// TaskTotals sums up the completed tasks of a user in a period. Tasks are
+// grouped by description, which is what identifies a piece of work, and by
+// UTC day of their start.
+type TaskTotals struct {
+	UserID        int                `json:"userId"`
+	TaskCount     int                `json:"taskCount"`
+	TotalSeconds  int64              `json:"totalSeconds"`
+	ByDescription []DescriptionTotal `json:"byDescription"`
+	ByDay         []DayTotal         `json:"byDay"`
+}
+
+type DescriptionTotal struct {
+	Description  string `json:"description"`
+	TaskCount    int    `json:"taskCount"`
+	TotalSeconds int64  `json:"totalSeconds"`
+}
+
+type DayTotal struct {
+	// Date is formatted as YYYY-MM-DD.
+	Date         string `json:"date"`
+	TaskCount    int    `json:"taskCount"`
+	TotalSeconds int64  `json:"totalSeconds"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// SearchResults groups full-text search hits by entity type, best match first.
+type SearchResults struct {
+	Query string          `json:"query"`
+	Users []UserSearchHit `json:"users"`
+	Tasks []TaskSearchHit `json:"tasks"`
+}
+
+// UserSearchHit carries highlighted fragments with matches wrapped in <mark>
+// tags. The rest of a fragment is HTML-escaped.
+type UserSearchHit struct {
+	ID               int     `json:"id"`
+	Surname          string  `json:"surname,omitempty"`
+	Name             string  `json:"name,omitempty"`
+	Patronymic       string  `json:"patronymic,omitempty"`
+	Rank             float64 `json:"rank"`
+	NameHighlight    string  `json:"nameHighlight"`
+	AddressHighlight string  `json:"addressHighlight,omitempty"`
+}
+
+type TaskSearchHit struct {
+	ID          int     `json:"id"`
+	UserID      int     `json:"userId"`
+	Description string  `json:"description"`
+	Rank        float64 `json:"rank"`
+	Highlight   string  `json:"highlight"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Types of the stream messages besides the event types they relay
+// (task.started, task.ended and user.deleted). Tasks cannot be paused, so
+// there is no task.paused event.
+const (
+	StreamSnapshot = "snapshot"
+	StreamTick     = "tick"
+)
+
+// StreamMessage is pushed to live timer clients over SSE and WebSocket. A
+// snapshot lists every running task the client follows and is sent when the
+// stream opens and whenever events may have been missed; ticks carry the
+// elapsed time of the same tasks.
+type StreamMessage struct {
+	Type    string    `json:"type"`
+	EventID string    `json:"eventId,omitempty"`
+	At      time.Time `json:"at"`
+	Task    *Task     `json:"task,omitempty"`
+	UserID  int       `json:"userId,omitempty"`
+	Timers  []Timer   `json:"timers,omitempty"`
+}
+
+// Timer is a running task with the time elapsed since it started.
+type Timer struct {
+	TaskID         int       `json:"taskId"`
+	UserID         int       `json:"userId"`
+	Description    string    `json:"description"`
+	StartTime      time.Time `json:"startTime"`
+	ElapsedSeconds int64     `json:"elapsedSeconds"`
+}
@@ -3,16 +3,25 @@ package models
 import "time"
 
 type Task struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"userId"`
-	Description string    `json:"description"`
-	StartTime   time.Time `json:"startTime"`
-	EndTime     time.Time `json:"endTime,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          int        `json:"id"`
+	UserID      int        `json:"userId"`
+	Description string     `json:"description"`
+	StartTime   time.Time  `json:"startTime"`
+	EndTime     *time.Time `json:"endTime,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+	Version     int        `json:"version"`
 }
 
 type Request struct {
-	UserID      uint   `json:"user_id"`
-	Description string `json:"description"`
+	UserID      uint   `json:"user_id" binding:"required,gt=0"`
+	Description string `json:"description" binding:"required,notblank,max=1000"`
+}
+
+// PeriodQuery selects the tasks of a user that started and ended within
+// [Start, End]. Running tasks are left out.
+type PeriodQuery struct {
+	Start string `form:"start" binding:"required,rfc3339"`
+	End   string `form:"end" binding:"required,rfc3339,rfc3339after=Start"`
 }
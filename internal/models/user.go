@@ -3,12 +3,40 @@ package models
 import "time"
 
 type User struct {
-    ID             int       `json:"id"`
-    PassportNumber string    `json:"passportNumber"`
-    Surname        string    `json:"surname,omitempty"`
-    Name           string    `json:"name,omitempty"`
-    Patronymic     string    `json:"patronymic,omitempty"`
-    Address        string    `json:"address,omitempty"`
-    CreatedAt      time.Time `json:"createdAt"`
-    UpdatedAt      time.Time `json:"updatedAt"`
+	ID             int        `json:"id"`
+	PassportNumber string     `json:"passportNumber" binding:"required,passport"`
+	Surname        string     `json:"surname,omitempty" binding:"max=100"`
+	Name           string     `json:"name,omitempty" binding:"max=100"`
+	Patronymic     string     `json:"patronymic,omitempty" binding:"max=100"`
+	Address        string     `json:"address,omitempty" binding:"max=255"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	DeletedAt      *time.Time `json:"deletedAt,omitempty"`
+	Version        int        `json:"version"`
+}
+
+// UserExport is the personal data bundle handed out on a subject access request.
+type UserExport struct {
+	User       User      `json:"user"`
+	Tasks      []Task    `json:"tasks"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// EraseRequest asks for a user's personal data to be erased. RequestedBy is
+// the authenticated caller, never taken from the request body.
+type EraseRequest struct {
+	RequestedBy       string `json:"-"`
+	Reason            string `json:"reason" binding:"max=1000"`
+	RetainTimeRecords bool   `json:"retainTimeRecords"`
+}
+
+// ErasureRecord is the audit trail entry left behind after a user's personal data was erased.
+type ErasureRecord struct {
+	ID                  int       `json:"id"`
+	UserID              int       `json:"userId"`
+	RequestedBy         string    `json:"requestedBy"`
+	Reason              string    `json:"reason,omitempty"`
+	RetainedTimeRecords bool      `json:"retainedTimeRecords"`
+	TasksAffected       int       `json:"tasksAffected"`
+	CreatedAt           time.Time `json:"createdAt"`
 }
@@ -0,0 +1,61 @@
+package models
+
+import "strings"
+
+type FilterOp string
+
+const (
+	OpEq       FilterOp = "eq"
+	OpNe       FilterOp = "ne"
+	OpContains FilterOp = "contains"
+	OpPrefix   FilterOp = "prefix"
+	OpIn       FilterOp = "in"
+	OpGt       FilterOp = "gt"
+	OpGte      FilterOp = "gte"
+	OpLt       FilterOp = "lt"
+	OpLte      FilterOp = "lte"
+)
+
+// FieldFilter is a single "field[op]=value" condition of a list query.
+type FieldFilter struct {
+	Field  string
+	Op     FilterOp
+	Values []string
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads a comma separated list of fields, each descending when
+// prefixed with "-", e.g. "surname,-createdAt". ok is false when a field is
+// empty.
+func ParseSort(spec string) (fields []SortField, ok bool) {
+	for _, part := range strings.Split(spec, ",") {
+		field := strings.TrimSpace(part)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if field == "" {
+			return nil, false
+		}
+		fields = append(fields, SortField{Field: field, Desc: desc})
+	}
+	return fields, true
+}
+
+// UserQuery describes GET /api/users: filters are combined with AND, results
+// are ordered by Sort and paged with an opaque cursor.
+type UserQuery struct {
+	Filters        []FieldFilter
+	Sort           []SortField
+	Limit          int
+	Cursor         string
+	IncludeDeleted bool
+}
+
+type UserPage struct {
+	Users []User `json:"data"`
+	Total int    `json:"total"`
+	Next  string `json:"next,omitempty"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types delivered to webhook subscribers.
+const (
+	EventTaskStarted = "task.started"
+	EventTaskEnded   = "task.ended"
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserDeleted = "user.deleted"
+)
+
+// Delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Event is the body POSTed to webhook subscribers.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+type WebhookSubscription struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"eventTypes"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type WebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,startswith=http"`
+	Secret     string   `json:"secret" binding:"required,min=16,max=255"`
+	EventTypes []string `json:"eventTypes" binding:"required,min=1,dive,oneof=task.started task.ended user.created user.updated user.deleted"`
+}
+
+// WebhookDelivery is an entry of the delivery log.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int             `json:"subscriptionId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+// OutgoingWebhook is a due delivery together with its target.
+type OutgoingWebhook struct {
+	DeliveryID int64
+	EventID    string
+	EventType  string
+	Payload    []byte
+	Attempts   int
+	URL        string
+	Secret     string
+}
+
+// WebhookAttempt is the outcome of one delivery attempt. A failed attempt
+// without NextAttemptAt gives up on the delivery.
+type WebhookAttempt struct {
+	Delivered     bool
+	StatusCode    int
+	Error         string
+	NextAttemptAt *time.Time
+}
+
+// OutboxEvent is a pending row of the transactional outbox. Payload is the
+// JSON encoded Event.
+type OutboxEvent struct {
+	ID            int64
+	EventID       string
+	Type          string
+	AggregateType string
+	AggregateID   int
+	Payload       []byte
+	CreatedAt     time.Time
+	Attempts      int
+}
@@ -0,0 +1,102 @@
+// Package outbox provides the sinks the outbox relay publishes domain events
+// to.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	db "time-tracker/internal/database"
+	"time-tracker/internal/models"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Sink receives every event at least once, in outbox order.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event models.OutboxEvent) error
+}
+
+// WebhookSink queues events for the matching webhook subscriptions; the
+// webhook dispatcher sends them.
+type WebhookSink struct {
+	repo *db.WebhookRepository
+}
+
+func NewWebhookSink(repo *db.WebhookRepository) *WebhookSink {
+	return &WebhookSink{repo: repo}
+}
+
+func (s *WebhookSink) Name() string { return "webhooks" }
+
+func (s *WebhookSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	return s.repo.Enqueue(ctx, event)
+}
+
+// StdoutSink writes one JSON document per line, which is handy for local
+// development and log based pipelines.
+type StdoutSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewStdoutSink(w io.Writer) *StdoutSink {
+	return &StdoutSink{w: w}
+}
+
+func (s *StdoutSink) Name() string { return "stdout" }
+
+func (s *StdoutSink) Publish(_ context.Context, event models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(event.Payload); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.w, "\n")
+	return err
+}
+
+// NATSSink publishes to "<prefix>.<event type>", e.g.
+// time-tracker.task.started. The event ID is sent as Nats-Msg-Id so that
+// JetStream streams drop the duplicates at-least-once delivery produces.
+type NATSSink struct {
+	conn    *nats.Conn
+	prefix  string
+	timeout time.Duration
+}
+
+// NewNATSSink connects in the background: the service starts while the
+// server is unreachable and events wait in the outbox until it is back.
+func NewNATSSink(url, prefix string, timeout time.Duration) (*NATSSink, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("time-tracker outbox"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.Timeout(timeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connect to NATS: %w", err)
+	}
+	return &NATSSink{conn: conn, prefix: prefix, timeout: timeout}, nil
+}
+
+func (s *NATSSink) Name() string { return "nats" }
+
+// Publish waits for the server to acknowledge the message with a PONG.
+func (s *NATSSink) Publish(_ context.Context, event models.OutboxEvent) error {
+	msg := nats.NewMsg(s.prefix + "." + event.Type)
+	msg.Data = event.Payload
+	msg.Header.Set(nats.MsgIdHdr, event.EventID)
+	if err := s.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	return s.conn.FlushTimeout(s.timeout)
+}
+
+func (s *NATSSink) Close() {
+	s.conn.Close()
+}
@@ -0,0 +1,125 @@
+package peopleinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/config"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// People is the enrichment data returned by the People info API.
+type People struct {
+	Surname    string `json:"surname"`
+	Name       string `json:"name"`
+	Patronymic string `json:"patronymic"`
+	Address    string `json:"address"`
+}
+
+// Client calls the external People info API described in the README.
+type Client struct {
+	url  string
+	http *http.Client
+}
+
+func NewClient(cfg config.PeopleInfoConfig) *Client {
+	return &Client{
+		url: cfg.URL,
+		http: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Enabled reports whether an API URL is configured.
+func (c *Client) Enabled() bool {
+	return c != nil && c.url != ""
+}
+
+// Lookup fetches the person owning the passport. Any failure is reported as
+// an *apperrors.ExternalAPIError.
+func (c *Client) Lookup(ctx context.Context, series, number string) (*People, error) {
+	logger.FromContext(ctx).Debug("Requesting people info",
+		"passportSerie", series,
+	)
+
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return nil, &apperrors.ExternalAPIError{Message: "Invalid info API URL"}
+	}
+	params := u.Query()
+	params.Set("passportSerie", series)
+	params.Set("passportNumber", number)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, &apperrors.ExternalAPIError{Message: "Error while making request to info API"}
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		metrics.ObservePeopleInfo(start, "transport")
+		logger.FromContext(ctx).Error("Error while making request to info API",
+			"error", err,
+		)
+		return nil, &apperrors.ExternalAPIError{Message: "Error while making request to info API"}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		metrics.ObservePeopleInfo(start, "status")
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		logger.FromContext(ctx).Error("Info API returned an error",
+			"status", resp.StatusCode,
+			"body", string(body),
+		)
+		return nil, &apperrors.ExternalAPIError{Message: fmt.Sprintf("Info API responded with status %d", resp.StatusCode)}
+	}
+
+	var people People
+	if err := json.NewDecoder(resp.Body).Decode(&people); err != nil {
+		metrics.ObservePeopleInfo(start, "decode")
+		logger.FromContext(ctx).Error("Error unmarshaling info from external API",
+			"error", err,
+		)
+		return nil, &apperrors.ExternalAPIError{Message: "Error unmarshaling info from external API"}
+	}
+	if people.Surname == "" || people.Name == "" || people.Address == "" {
+		metrics.ObservePeopleInfo(start, "incomplete")
+		return nil, &apperrors.ExternalAPIError{Message: "Got not complete data from external API"}
+	}
+
+	metrics.ObservePeopleInfo(start, "")
+	logger.FromContext(ctx).Info("People info received")
+	return &people, nil
+}
+
+// Ping checks that the API answers at all; client errors are fine since no
+// passport is sent.
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("info API responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package problem renders errors as RFC 7807 application/problem+json.
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const ContentType = "application/problem+json"
+
+// typeBase prefixes the problem type URIs; they are relative references
+// resolved against the API origin.
+const typeBase = "/problems/"
+
+// Problem is the RFC 7807 body. RequestID and Errors are extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func newProblem(status int, slug, detail string) Problem {
+	return Problem{
+		Type:   typeBase + slug,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// FromError maps an error to a problem. Messages of apperrors are meant for
+// clients and are used as detail; anything else, database errors in
+// particular, only produces a generic description.
+func FromError(err error) Problem {
+	var (
+		badRequest         *apperrors.BadRequestError
+		validation         *apperrors.ValidationError
+		unauthorized       *apperrors.UnauthorizedError
+		forbidden          *apperrors.ForbiddenError
+		notFound           *apperrors.NotFoundError
+		noUser             *apperrors.NoUserError
+		noTask             *apperrors.NoTaskError
+		noRowsAffected     *apperrors.NoRowsAffectedError
+		duplicate          *apperrors.DuplicateKeyError
+		alreadyEnded       *apperrors.TaskAlreadyEndedError
+		preconditionFailed *apperrors.PreconditionFailedError
+		preconditionNeeded *apperrors.PreconditionRequiredError
+		unsupportedMedia   *apperrors.UnsupportedMediaTypeError
+		externalAPI        *apperrors.ExternalAPIError
+		keyReused          *apperrors.IdempotencyKeyReusedError
+		inProgress         *apperrors.RequestInProgressError
+		tooManyRequests    *apperrors.TooManyRequestsError
+		pgErr              *pgconn.PgError
+	)
+
+	switch {
+	case errors.As(err, &validation):
+		p := newProblem(http.StatusUnprocessableEntity, "validation-error", validation.Message)
+		for field, msg := range validation.Fields {
+			p.Errors = append(p.Errors, FieldError{Field: field, Message: msg})
+		}
+		sort.Slice(p.Errors, func(i, j int) bool { return p.Errors[i].Field < p.Errors[j].Field })
+		return p
+	case errors.As(err, &badRequest):
+		return newProblem(http.StatusBadRequest, "bad-request", badRequest.Message)
+	case errors.As(err, &unauthorized):
+		return newProblem(http.StatusUnauthorized, "unauthorized", unauthorized.Message)
+	case errors.As(err, &forbidden):
+		return newProblem(http.StatusForbidden, "forbidden", forbidden.Message)
+	case errors.As(err, &notFound):
+		return newProblem(http.StatusNotFound, "not-found", notFound.Message)
+	case errors.As(err, &noUser):
+		return newProblem(http.StatusNotFound, "user-not-found", noUser.Message)
+	case errors.As(err, &noTask):
+		return newProblem(http.StatusNotFound, "task-not-found", noTask.Message)
+	case errors.As(err, &noRowsAffected):
+		return newProblem(http.StatusNotFound, "not-found", noRowsAffected.Message)
+	case errors.Is(err, pgx.ErrNoRows):
+		return newProblem(http.StatusNotFound, "not-found", "The requested resource does not exist")
+	case errors.As(err, &duplicate):
+		return newProblem(http.StatusConflict, "duplicate", duplicate.Message)
+	case errors.As(err, &alreadyEnded):
+		return newProblem(http.StatusConflict, "task-already-ended", alreadyEnded.Message)
+	case errors.As(err, &preconditionFailed):
+		return newProblem(http.StatusPreconditionFailed, "precondition-failed", preconditionFailed.Message)
+	case errors.As(err, &preconditionNeeded):
+		return newProblem(http.StatusPreconditionRequired, "precondition-required", preconditionNeeded.Message)
+	case errors.As(err, &unsupportedMedia):
+		return newProblem(http.StatusUnsupportedMediaType, "unsupported-media-type", unsupportedMedia.Message)
+	case errors.As(err, &keyReused):
+		return newProblem(http.StatusUnprocessableEntity, "idempotency-key-reused", keyReused.Message)
+	case errors.As(err, &inProgress):
+		return newProblem(http.StatusConflict, "request-in-progress", inProgress.Message)
+	case errors.As(err, &tooManyRequests):
+		return newProblem(http.StatusTooManyRequests, "rate-limited", tooManyRequests.Message)
+	case errors.As(err, &externalAPI):
+		return newProblem(http.StatusBadGateway, "external-api-error", externalAPI.Message)
+	case errors.As(err, &pgErr):
+		return fromPgError(pgErr)
+	}
+	return newProblem(http.StatusInternalServerError, "internal-error", "An unexpected error occurred")
+}
+
+// fromPgError maps constraint violations by SQLSTATE. Only the constraint
+// class is disclosed, never the statement or the offending values.
+func fromPgError(err *pgconn.PgError) Problem {
+	switch err.Code {
+	case "23505": // unique_violation
+		return newProblem(http.StatusConflict, "duplicate", "A resource with the same unique attributes already exists")
+	case "23503": // foreign_key_violation
+		return newProblem(http.StatusConflict, "reference-violation", "The resource references or is referenced by another resource")
+	case "23502", "23514": // not_null_violation, check_violation
+		return newProblem(http.StatusUnprocessableEntity, "constraint-violation", "The request violates a data constraint")
+	case "22001", "22P02", "22007", "22008": // string too long, invalid text representation, invalid datetime
+		return newProblem(http.StatusBadRequest, "bad-request", "The request contains a value of the wrong format or size")
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return newProblem(http.StatusServiceUnavailable, "retry", "The request conflicted with a concurrent one, please retry")
+	}
+	return newProblem(http.StatusInternalServerError, "internal-error", "An unexpected error occurred")
+}
+
+// Write renders p for the current request.
+func Write(c *gin.Context, p Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = requestid.FromContext(c.Request.Context())
+	}
+	c.Header("Content-Type", ContentType)
+	c.Render(p.Status, problemRender{p})
+	c.Abort()
+}
+
+// Middleware renders the last error attached with c.Error unless the
+// handler has already written a response.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		p := FromError(err)
+		if p.Status >= http.StatusInternalServerError {
+			logger.FromContext(c.Request.Context()).Error("Request failed",
+				"status", p.Status,
+				"error", err,
+			)
+		}
+		Write(c, p)
+	}
+}
+
+// Recovery turns panics into a 500 problem.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		logger.FromContext(c.Request.Context()).Error("Panic while serving request",
+			"panic", fmt.Sprint(recovered),
+		)
+		Write(c, newProblem(http.StatusInternalServerError, "internal-error", "An unexpected error occurred"))
+	})
+}
+
+// NoRoute answers unknown paths and methods.
+func NoRoute(c *gin.Context) {
+	Write(c, newProblem(http.StatusNotFound, "not-found", "No such endpoint"))
+}
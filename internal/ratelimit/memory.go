@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets of a single instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	tat := s.tats[key]
+	next := tat
+	if next.Before(now) {
+		next = now
+	}
+	next = next.Add(limit.Emission())
+	if next.Sub(now) > limit.Tolerance() {
+		return limit.Result(tat, now, false), nil
+	}
+	s.tats[key] = next
+	return limit.Result(next, now, true), nil
+}
+
+// sweep forgets the buckets that have refilled completely.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, tat := range s.tats {
+		if !tat.After(now) {
+			delete(s.tats, key)
+		}
+	}
+}
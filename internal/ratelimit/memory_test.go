@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	// An emission interval of 500ms and a tolerance of 1.5s.
+	limit := Limit{Rate: 2, Burst: 3}
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ms := time.Millisecond
+
+	store := NewMemoryStore()
+	steps := []struct {
+		name       string
+		key        string
+		at         time.Duration
+		want       Result
+		reset      string
+		retryAfter string
+	}{
+		{name: "first of burst", key: "a", at: 0, want: Result{Allowed: true, Remaining: 2, Reset: 500 * ms}, reset: "1"},
+		{name: "second of burst", key: "a", at: 0, want: Result{Allowed: true, Remaining: 1, Reset: 1000 * ms}, reset: "1"},
+		{name: "last of burst", key: "a", at: 0, want: Result{Allowed: true, Remaining: 0, Reset: 1500 * ms}, reset: "2"},
+		{name: "burst exhausted", key: "a", at: 0, want: Result{Reset: 1500 * ms, RetryAfter: 500 * ms}, reset: "2", retryAfter: "1"},
+		{name: "still exhausted", key: "a", at: 250 * ms, want: Result{Reset: 1250 * ms, RetryAfter: 250 * ms}, reset: "2", retryAfter: "1"},
+		{name: "other key unaffected", key: "b", at: 250 * ms, want: Result{Allowed: true, Remaining: 2, Reset: 500 * ms}, reset: "1"},
+		{name: "token back after one emission", key: "a", at: 500 * ms, want: Result{Allowed: true, Remaining: 0, Reset: 1500 * ms}, reset: "2"},
+		{name: "exhausted again", key: "a", at: 500 * ms, want: Result{Reset: 1500 * ms, RetryAfter: 500 * ms}, reset: "2", retryAfter: "1"},
+		{name: "refilled", key: "a", at: 2000 * ms, want: Result{Allowed: true, Remaining: 2, Reset: 500 * ms}, reset: "1"},
+	}
+	for _, step := range steps {
+		got, err := store.Take(context.Background(), step.key, limit, t0.Add(step.at))
+		if err != nil {
+			t.Fatalf("%s: Take() error = %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: Take() = %+v, want %+v", step.name, got, step.want)
+		}
+		if reset := seconds(got.Reset); reset != step.reset {
+			t.Errorf("%s: RateLimit-Reset = %s, want %s", step.name, reset, step.reset)
+		}
+		if !got.Allowed {
+			if retryAfter := seconds(got.RetryAfter); retryAfter != step.retryAfter {
+				t.Errorf("%s: Retry-After = %s, want %s", step.name, retryAfter, step.retryAfter)
+			}
+		}
+	}
+}
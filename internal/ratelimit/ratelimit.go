@@ -0,0 +1,126 @@
+// Package ratelimit throttles clients with a token bucket per route group and
+// caller, implemented as the generic cell rate algorithm (GCRA): a bucket is
+// a single timestamp, the theoretical arrival time (TAT) of the next request,
+// which makes it cheap to keep in memory or in a shared database.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/auth"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limit allows Rate requests per second on average and bursts of up to
+// Burst requests.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Emission is the time it takes to refill one token.
+func (l Limit) Emission() time.Duration {
+	return time.Duration(float64(time.Second) / l.Rate)
+}
+
+// Tolerance is how far the TAT may run ahead of the current time.
+func (l Limit) Tolerance() time.Duration {
+	return time.Duration(l.Burst) * l.Emission()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, set when not allowed.
+	RetryAfter time.Duration
+}
+
+// Result describes the bucket of a key. tat is the stored TAT after an
+// allowed request, or the unchanged one after a rejected request.
+func (l Limit) Result(tat, now time.Time, allowed bool) Result {
+	res := Result{Allowed: allowed}
+	if ahead := tat.Sub(now); ahead > 0 {
+		res.Reset = ahead
+	}
+	if allowed {
+		res.Remaining = int((l.Tolerance() - res.Reset) / l.Emission())
+	} else {
+		res.RetryAfter = tat.Add(l.Emission()).Sub(now.Add(l.Tolerance()))
+	}
+	return res
+}
+
+// Store keeps the buckets. Take consumes one token of key if available.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Limiter applies the limits of the configured route groups.
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// New returns a limiter; with a nil store nothing is limited.
+func New(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// Middleware limits the requests of a route group per authenticated
+// subject, or per client IP for anonymous callers, and reports the state of
+// the bucket in RateLimit-* headers. If the store fails the request is let
+// through. It must run after auth.Middleware.
+func (l *Limiter) Middleware(group string) gin.HandlerFunc {
+	limit, ok := l.limits[group]
+	if l.store == nil || !ok || limit.Rate <= 0 || limit.Burst <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy := strconv.Itoa(limit.Burst) + ";w=" + seconds(limit.Tolerance())
+
+	return func(c *gin.Context) {
+		res, err := l.store.Take(c, group+":"+clientKey(c), limit, time.Now())
+		if err != nil {
+			logger.FromContext(c).Warn("Rate limiter unavailable, request let through",
+				"group", group,
+				"error", err,
+			)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", seconds(res.Reset))
+		if !res.Allowed {
+			metrics.RateLimited(group)
+			c.Header("Retry-After", seconds(res.RetryAfter))
+			c.Error(&apperrors.TooManyRequestsError{Message: "Rate limit exceeded, retry in " + seconds(res.RetryAfter) + "s"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func clientKey(c *gin.Context) string {
+	if principal, ok := auth.FromContext(c.Request.Context()); ok {
+		return "user:" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds rounds d up to whole seconds as the RateLimit headers require.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
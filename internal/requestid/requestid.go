@@ -0,0 +1,47 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Header = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// Middleware propagates the caller's X-Request-ID or generates a new one,
+// echoing it back in the response.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, id := NewContext(c.Request.Context(), c.GetHeader(Header))
+		c.Header(Header, id)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// NewContext stores the caller's request ID in ctx, or a new one when id is
+// empty or implausibly long, and returns the ID used.
+func NewContext(ctx context.Context, id string) (context.Context, string) {
+	if id == "" || len(id) > 128 {
+		id = generate()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id), id
+}
+
+// FromContext returns the request ID stored by Middleware or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"time-tracker/internal/config"
+	"time-tracker/internal/logger"
+)
+
+// Server wraps http.Server with the configured timeouts and an optional TLS
+// certificate that can be swapped at runtime.
+type Server struct {
+	srv *http.Server
+	cfg config.HTTPConfig
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func New(cfg config.HTTPConfig, handler http.Handler) (*Server, error) {
+	s := &Server{
+		cfg: cfg,
+		srv: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		},
+	}
+	if cfg.TLSEnabled() {
+		if err := s.ReloadCertificate(); err != nil {
+			return nil, err
+		}
+		s.srv.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				s.mu.RLock()
+				defer s.mu.RUnlock()
+				return s.cert, nil
+			},
+		}
+	}
+	return s, nil
+}
+
+// ReloadCertificate re-reads the certificate and key files. New handshakes
+// use the new pair; on error the previous one stays in use.
+func (s *Server) ReloadCertificate() error {
+	if !s.cfg.TLSEnabled() {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	s.mu.Lock()
+	s.cert = &cert
+	s.mu.Unlock()
+
+	logger.Logger.Info("TLS certificate loaded",
+		"certFile", s.cfg.TLSCertFile,
+	)
+	return nil
+}
+
+// TLSConfig returns the TLS configuration serving the current certificate,
+// or nil when TLS is disabled. Other listeners can share it so that SIGHUP
+// reloads their certificate too.
+func (s *Server) TLSConfig() *tls.Config {
+	return s.srv.TLSConfig
+}
+
+// Run serves until ctx is cancelled, then stops accepting connections and
+// waits up to the shutdown timeout for in-flight requests.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.FromContext(ctx).Info("HTTP server started",
+			"addr", s.cfg.Addr,
+			"tls", s.cfg.TLSEnabled(),
+		)
+		if s.cfg.TLSEnabled() {
+			errCh <- s.srv.ServeTLS(ln, "", "")
+		} else {
+			errCh <- s.srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.FromContext(ctx).Info("Shutting down HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown HTTP server: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.FromContext(ctx).Info("HTTP server stopped")
+	return nil
+}
@@ -0,0 +1,484 @@
+// Package memory keeps users and tasks in memory with the semantics of the
+// Postgres repositories, for tests and local experiments. It does not write
+// audit events or outbox events.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+	"time-tracker/internal/storage"
+)
+
+// Store implements storage.UserRepository and storage.TaskRepository. It is
+// safe for concurrent use; every method works on a consistent snapshot, as
+// a transaction would.
+type Store struct {
+	mu       sync.Mutex
+	users    map[int]*models.User
+	tasks    map[int]*models.Task
+	erasures []models.ErasureRecord
+	// erasedTotals holds the daily time records of erased users.
+	erasedTotals map[string]erasedTotal
+	lastUser     int
+	lastTask     int
+}
+
+type erasedTotal struct {
+	tasks    int
+	duration time.Duration
+}
+
+func New() *Store {
+	return &Store{
+		users:        make(map[int]*models.User),
+		tasks:        make(map[int]*models.Task),
+		erasedTotals: make(map[string]erasedTotal),
+	}
+}
+
+// timestamp is the current time at the microsecond precision of Postgres.
+func (s *Store) timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func noUser(id int) error {
+	return &apperrors.NoUserError{Message: fmt.Sprintf("User with id %v doesn't exist", id)}
+}
+
+func noTask(id int) error {
+	return &apperrors.NoTaskError{Message: fmt.Sprintf("No task with id %v", id)}
+}
+
+func userChangedError(current *models.User) error {
+	return &apperrors.PreconditionFailedError{Message: fmt.Sprintf("User with id %v has been modified, current version is %v", current.ID, current.Version)}
+}
+
+// user returns the stored user. The caller holds the lock.
+func (s *Store) user(id int, includeDeleted bool) (*models.User, error) {
+	user, ok := s.users[id]
+	if !ok || (!includeDeleted && user.DeletedAt != nil) {
+		return nil, noUser(id)
+	}
+	return user, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	s.lastUser++
+	stored := models.User{
+		ID:             s.lastUser,
+		PassportNumber: user.PassportNumber,
+		Surname:        user.Surname,
+		Name:           user.Name,
+		Patronymic:     user.Patronymic,
+		Address:        user.Address,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Version:        1,
+	}
+	s.users[stored.ID] = &stored
+	*user = stored
+	return nil
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id int, includeDeleted bool) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.user(id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	found := *user
+	return &found, nil
+}
+
+func (s *Store) GetUsers(ctx context.Context, query models.UserQuery) (*models.UserPage, error) {
+	listing, err := storage.ResolveUserQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matches []models.User
+	for _, user := range s.users {
+		if !query.IncludeDeleted && user.DeletedAt != nil {
+			continue
+		}
+		if matchesAll(user, listing.Filters) {
+			matches = append(matches, *user)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return compareUsers(&matches[i], &matches[j], listing.Keys) < 0
+	})
+
+	page := &models.UserPage{Users: []models.User{}, Total: len(matches)}
+	for i := range matches {
+		if listing.After != nil && compareToKeyset(&matches[i], listing.Keys, listing.After) <= 0 {
+			continue
+		}
+		if len(page.Users) == query.Limit {
+			page.Next = storage.EncodeUserCursor(listing.Keys, &page.Users[query.Limit-1])
+			break
+		}
+		page.Users = append(page.Users, matches[i])
+	}
+	return page, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user *models.User, cond models.Precondition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.user(user.ID, false)
+	if err != nil {
+		return err
+	}
+	if !cond.Matches(current.Version) {
+		return userChangedError(current)
+	}
+
+	current.PassportNumber, current.Surname, current.Name, current.Patronymic, current.Address =
+		user.PassportNumber, user.Surname, user.Name, user.Patronymic, user.Address
+	current.UpdatedAt = s.timestamp()
+	current.Version++
+	*user = *current
+	return nil
+}
+
+func (s *Store) PatchUser(ctx context.Context, id int, cond models.Precondition, apply func(user *models.User) error) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.user(id, false)
+	if err != nil {
+		return nil, err
+	}
+	if !cond.Matches(current.Version) {
+		return nil, userChangedError(current)
+	}
+
+	patched := *current
+	if err := apply(&patched); err != nil {
+		return nil, err
+	}
+	// Only the personal data is written back, like the UPDATE of Postgres.
+	current.PassportNumber, current.Surname, current.Name, current.Patronymic, current.Address =
+		patched.PassportNumber, patched.Surname, patched.Name, patched.Patronymic, patched.Address
+	current.UpdatedAt = s.timestamp()
+	current.Version++
+	user := *current
+	return &user, nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id int, cond models.Precondition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.user(id, false)
+	if err != nil {
+		return err
+	}
+	if !cond.Matches(current.Version) {
+		return userChangedError(current)
+	}
+
+	now := s.timestamp()
+	current.DeletedAt = &now
+	current.Version++
+	for _, task := range s.tasks {
+		if task.UserID == id && task.DeletedAt == nil {
+			task.DeletedAt = &now
+			task.Version++
+		}
+	}
+	return nil
+}
+
+func (s *Store) RestoreUser(ctx context.Context, id int) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.user(id, true)
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt := current.DeletedAt; deletedAt != nil {
+		current.DeletedAt = nil
+		current.Version++
+		// Tasks deleted before the user stay deleted.
+		for _, task := range s.tasks {
+			if task.UserID == id && task.DeletedAt != nil && task.DeletedAt.Equal(*deletedAt) {
+				task.DeletedAt = nil
+				task.Version++
+			}
+		}
+	}
+	user := *current
+	return &user, nil
+}
+
+func (s *Store) ExportUser(ctx context.Context, id int) (*models.UserExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.user(id, true)
+	if err != nil {
+		return nil, err
+	}
+	export := &models.UserExport{User: *user, Tasks: []models.Task{}}
+	for _, task := range s.tasks {
+		if task.UserID == id {
+			export.Tasks = append(export.Tasks, *task)
+		}
+	}
+	sort.Slice(export.Tasks, func(i, j int) bool {
+		return export.Tasks[i].StartTime.Before(export.Tasks[j].StartTime)
+	})
+	export.ExportedAt = time.Now()
+	return export, nil
+}
+
+func (s *Store) EraseUser(ctx context.Context, id int, req *models.EraseRequest) (*models.ErasureRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.user(id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.timestamp()
+	record := models.ErasureRecord{
+		ID:                  len(s.erasures) + 1,
+		UserID:              id,
+		RequestedBy:         req.RequestedBy,
+		Reason:              req.Reason,
+		RetainedTimeRecords: req.RetainTimeRecords,
+		CreatedAt:           now,
+	}
+	for taskID, task := range s.tasks {
+		if task.UserID != id {
+			continue
+		}
+		record.TasksAffected++
+		if req.RetainTimeRecords && task.EndTime != nil {
+			day := task.StartTime.UTC().Format(time.DateOnly)
+			total := s.erasedTotals[day]
+			total.tasks++
+			total.duration += task.EndTime.Sub(task.StartTime)
+			s.erasedTotals[day] = total
+		}
+		delete(s.tasks, taskID)
+	}
+
+	user.PassportNumber, user.Surname, user.Name, user.Patronymic, user.Address = "", "", "", "", ""
+	user.UpdatedAt = now
+	user.Version++
+
+	s.erasures = append(s.erasures, record)
+	return &record, nil
+}
+
+func (s *Store) StartTask(ctx context.Context, userID int, description string) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.user(userID, false); err != nil {
+		return nil, err
+	}
+
+	now := s.timestamp()
+	s.lastTask++
+	task := &models.Task{
+		ID:          s.lastTask,
+		UserID:      userID,
+		Description: description,
+		StartTime:   now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	s.tasks[task.ID] = task
+	started := *task
+	return &started, nil
+}
+
+func (s *Store) EndTask(ctx context.Context, taskID int, cond models.Precondition) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[taskID]
+	if !ok || task.DeletedAt != nil {
+		return nil, noTask(taskID)
+	}
+	if !cond.Matches(task.Version) {
+		return nil, &apperrors.PreconditionFailedError{Message: fmt.Sprintf("Task with id %v has been modified, current version is %v", taskID, task.Version)}
+	}
+	if task.EndTime != nil {
+		return nil, &apperrors.TaskAlreadyEndedError{Message: "Task already ended"}
+	}
+
+	now := s.timestamp()
+	task.EndTime = &now
+	task.UpdatedAt = now
+	task.Version++
+	ended := *task
+	return &ended, nil
+}
+
+func (s *Store) GetTaskByID(ctx context.Context, id int, includeDeleted bool) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok || (!includeDeleted && task.DeletedAt != nil) {
+		return nil, noTask(id)
+	}
+	found := *task
+	return &found, nil
+}
+
+// GetUserTasksByPeriod returns nil rather than an empty slice when there are
+// no tasks, like the Postgres repository.
+func (s *Store) GetUserTasksByPeriod(ctx context.Context, userID int, start, end time.Time, includeDeleted bool) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tasks []models.Task
+	for _, task := range s.tasks {
+		if task.UserID != userID || task.EndTime == nil || (!includeDeleted && task.DeletedAt != nil) {
+			continue
+		}
+		if task.StartTime.Before(start) || task.EndTime.After(end) {
+			continue
+		}
+		tasks = append(tasks, *task)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		di, dj := tasks[i].EndTime.Sub(tasks[i].StartTime), tasks[j].EndTime.Sub(tasks[j].StartTime)
+		if di != dj {
+			return di > dj
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, nil
+}
+
+// matchesAll evaluates filters the way the SQL of the Postgres repository
+// does: text comparisons ignore case.
+func matchesAll(user *models.User, filters []storage.Filter) bool {
+	for _, f := range filters {
+		if !matches(storage.UserValue(user, f.Field), f) {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(value interface{}, f storage.Filter) bool {
+	if f.Kind == storage.TextField {
+		text := strings.ToLower(value.(string))
+		want := strings.ToLower(f.Values[0].(string))
+		switch f.Op {
+		case models.OpContains:
+			return strings.Contains(text, want)
+		case models.OpPrefix:
+			return strings.HasPrefix(text, want)
+		case models.OpIn:
+			for _, v := range f.Values {
+				if text == strings.ToLower(v.(string)) {
+					return true
+				}
+			}
+			return false
+		case models.OpNe:
+			return text != want
+		}
+		return text == want
+	}
+
+	if f.Op == models.OpIn {
+		for _, v := range f.Values {
+			if compareValues(value, v) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	c := compareValues(value, f.Values[0])
+	switch f.Op {
+	case models.OpNe:
+		return c != 0
+	case models.OpGt:
+		return c > 0
+	case models.OpGte:
+		return c >= 0
+	case models.OpLt:
+		return c < 0
+	case models.OpLte:
+		return c <= 0
+	}
+	return c == 0
+}
+
+// compareValues orders two values of the same field. Text is compared byte
+// by byte, which matches the C collation of Postgres.
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		b := b.(int)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+func compareUsers(a, b *models.User, keys []storage.SortKey) int {
+	for _, key := range keys {
+		c := compareValues(storage.UserValue(a, key.Field), storage.UserValue(b, key.Field))
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareToKeyset orders a user against the position of a cursor.
+func compareToKeyset(user *models.User, keys []storage.SortKey, after []interface{}) int {
+	for i, key := range keys {
+		c := compareValues(storage.UserValue(user, key.Field), after[i])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
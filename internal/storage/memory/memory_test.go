@@ -0,0 +1,9 @@
+package memory_test
+
+import (
+	"testing"
+
+	"time-tracker/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) { storagetest.Run(t, storagetest.Memory) }
@@ -0,0 +1,62 @@
+// Package storage defines the repositories the API handlers work with.
+// database implements them on Postgres and memory implements them in
+// memory; storagetest checks that both behave alike.
+package storage
+
+import (
+	"context"
+	"time"
+
+	"time-tracker/internal/models"
+)
+
+// UserRepository stores users. Deleted users are kept, hidden from reads
+// unless includeDeleted is set, until they are purged. Writes taking a
+// precondition fail with apperrors.PreconditionFailedError when it does not
+// match the current version, and every write bumps the version.
+type UserRepository interface {
+	// CreateUser stores a new user and fills in the ID, timestamps and
+	// version.
+	CreateUser(ctx context.Context, user *models.User) error
+	// GetUserByID fails with apperrors.NoUserError for a missing user.
+	GetUserByID(ctx context.Context, id int, includeDeleted bool) (*models.User, error)
+	// GetUsers returns a page of the users matching the query, the total
+	// number of matches and the cursor of the next page. Invalid fields,
+	// operators, values and cursors fail with apperrors.BadRequestError.
+	GetUsers(ctx context.Context, query models.UserQuery) (*models.UserPage, error)
+	// UpdateUser replaces the personal data of a user and refreshes user
+	// with the stored row.
+	UpdateUser(ctx context.Context, user *models.User, cond models.Precondition) error
+	// PatchUser stores the user as modified by apply, which works on the
+	// current version. An error of apply is returned as is.
+	PatchUser(ctx context.Context, id int, cond models.Precondition, apply func(user *models.User) error) (*models.User, error)
+	// DeleteUser soft-deletes a user together with their tasks.
+	DeleteUser(ctx context.Context, id int, cond models.Precondition) error
+	// RestoreUser undoes DeleteUser, tasks included. A user that is not
+	// deleted is returned unchanged.
+	RestoreUser(ctx context.Context, id int) (*models.User, error)
+	// ExportUser returns a user, deleted or not, with all their tasks in
+	// order of start.
+	ExportUser(ctx context.Context, id int) (*models.UserExport, error)
+	// EraseUser blanks the personal data of a user, deleted or not, and
+	// removes their tasks. Retained time records are only kept as daily
+	// totals that no longer refer to the user.
+	EraseUser(ctx context.Context, id int, req *models.EraseRequest) (*models.ErasureRecord, error)
+}
+
+// TaskRepository stores tasks. A task runs from its start until it is
+// ended; a user can run several tasks at once.
+type TaskRepository interface {
+	// StartTask starts a task now. It fails with apperrors.NoUserError when
+	// the user is missing or deleted.
+	StartTask(ctx context.Context, userID int, description string) (*models.Task, error)
+	// EndTask ends a running task now. Ending a task twice fails with
+	// apperrors.TaskAlreadyEndedError, a missing or deleted one with
+	// apperrors.NoTaskError.
+	EndTask(ctx context.Context, taskID int, cond models.Precondition) (*models.Task, error)
+	// GetTaskByID fails with apperrors.NoTaskError for a missing task.
+	GetTaskByID(ctx context.Context, id int, includeDeleted bool) (*models.Task, error)
+	// GetUserTasksByPeriod returns the tasks of a user that started and
+	// ended within [start, end], longest first. Running tasks are left out.
+	GetUserTasksByPeriod(ctx context.Context, userID int, start, end time.Time, includeDeleted bool) ([]models.Task, error)
+}
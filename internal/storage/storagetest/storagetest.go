@@ -0,0 +1,113 @@
+// Package storagetest checks that an implementation of the storage
+// interfaces behaves the way the API relies on. Hook it into the tests of a
+// backend with
+//
+//	func TestConformance(t *testing.T) { storagetest.Run(t, storagetest.Memory) }
+//
+// Text ordering is only checked on data that sorts the same under the C and
+// the common linguistic collations of Postgres.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	db "time-tracker/internal/database"
+	"time-tracker/internal/migrate"
+	"time-tracker/internal/storage"
+	"time-tracker/internal/storage/memory"
+	"time-tracker/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DatabaseURLEnv names the variable holding the Postgres database Postgres
+// runs against. Its tables are emptied before every test.
+const DatabaseURLEnv = "STORAGETEST_DATABASE_URL"
+
+type Backend struct {
+	Users storage.UserRepository
+	Tasks storage.TaskRepository
+	// Idempotency is nil for backends that keep no Idempotency-Keys.
+	Idempotency *db.IdempotencyRepository
+}
+
+// Run runs the suite. open is called once per test and must return empty
+// storage.
+func Run(t *testing.T, open func(t *testing.T) Backend) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, b Backend)
+	}{
+		{"UserLifecycle", testUserLifecycle},
+		{"UserPreconditions", testUserPreconditions},
+		{"PatchUser", testPatchUser},
+		{"DeleteAndRestoreUser", testDeleteAndRestoreUser},
+		{"FilterUsers", testFilterUsers},
+		{"InvalidUserQueries", testInvalidUserQueries},
+		{"PageUsers", testPageUsers},
+		{"StartAndEndTask", testStartAndEndTask},
+		{"OpenTaskRules", testOpenTaskRules},
+		{"ConcurrentEndTask", testConcurrentEndTask},
+		{"TasksByPeriod", testTasksByPeriod},
+		{"ExportUser", testExportUser},
+		{"EraseUser", testEraseUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, open(t))
+		})
+	}
+}
+
+// Memory opens a new in-memory store.
+func Memory(t *testing.T) Backend {
+	store := memory.New()
+	return Backend{Users: store, Tasks: store}
+}
+
+// Postgres opens the database named by DatabaseURLEnv, migrates it and
+// empties it. The test is skipped when the variable is not set.
+func Postgres(t *testing.T) Backend {
+	url := os.Getenv(DatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", DatabaseURLEnv)
+	}
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `TRUNCATE users, tasks, erasure_requests, erased_time_totals, audit_events, outbox, idempotency_keys RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("empty tables: %v", err)
+	}
+	return Backend{Users: db.NewUserRepository(pool), Tasks: db.NewTaskRepository(pool), Idempotency: db.NewIdempotencyRepository(pool)}
+}
+
+// wantError fails the test unless err is, or wraps, an error of type E.
+func wantError[E error](t *testing.T, err error) {
+	t.Helper()
+	var target E
+	if !errors.As(err, &target) {
+		t.Fatalf("got error %v (%T), want %T", err, err, target)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package storagetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+)
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
+
+// runTask starts a task and ends it after d.
+func runTask(t *testing.T, b Backend, userID int, description string, d time.Duration) *models.Task {
+	t.Helper()
+	task, err := b.Tasks.StartTask(ctx, userID, description)
+	must(t, err)
+	time.Sleep(d)
+	task, err = b.Tasks.EndTask(ctx, task.ID, models.Precondition{})
+	must(t, err)
+	return task
+}
+
+// responseHash stands for the request hash of the recorded responses.
+var responseHash = strings.Repeat("0", 64)
+
+// recordResponse stores task as the response to the request sent with key.
+func recordResponse(t *testing.T, b Backend, key string, task *models.Task) {
+	t.Helper()
+	stored, err := b.Idempotency.Reserve(ctx, "dpo", key, responseHash, time.Hour, time.Minute)
+	must(t, err)
+	if stored != nil {
+		t.Fatalf("key %s already has a response", key)
+	}
+	body, err := json.Marshal(task)
+	must(t, err)
+	header := map[string]string{"Content-Type": "application/json; charset=utf-8"}
+	must(t, b.Idempotency.Complete(ctx, "dpo", key, &models.StoredResponse{Status: http.StatusCreated, Header: header, Body: body}))
+}
+
+func taskIDs(tasks []models.Task) []int {
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func testStartAndEndTask(t *testing.T, b Backend) {
+	user := seedUsers(t, b, [2]string{"Ivanov", "Ivan"})[0]
+
+	started, err := b.Tasks.StartTask(ctx, user.ID, "Write a report")
+	must(t, err)
+	if started.ID <= 0 || started.UserID != user.ID || started.Description != "Write a report" || started.Version != 1 ||
+		started.StartTime.IsZero() || started.EndTime != nil || started.DeletedAt != nil {
+		t.Fatalf("started task = %+v", started)
+	}
+	got, err := b.Tasks.GetTaskByID(ctx, started.ID, false)
+	must(t, err)
+	if got.Description != started.Description || !got.StartTime.Equal(started.StartTime) || got.EndTime != nil {
+		t.Fatalf("GetTaskByID = %+v, want %+v", got, started)
+	}
+
+	ended, err := b.Tasks.EndTask(ctx, started.ID, models.Precondition{Versions: []int{started.Version}})
+	must(t, err)
+	if ended.EndTime == nil || ended.EndTime.Before(ended.StartTime) || ended.Version != started.Version+1 || !ended.StartTime.Equal(started.StartTime) {
+		t.Fatalf("ended task = %+v", ended)
+	}
+	got, err = b.Tasks.GetTaskByID(ctx, started.ID, false)
+	must(t, err)
+	if got.EndTime == nil || !got.EndTime.Equal(*ended.EndTime) || got.Version != ended.Version {
+		t.Fatalf("GetTaskByID after end = %+v, want %+v", got, ended)
+	}
+
+	_, err = b.Tasks.GetTaskByID(ctx, started.ID+1000, true)
+	wantError[*apperrors.NoTaskError](t, err)
+	_, err = b.Tasks.StartTask(ctx, user.ID+1000, "Nobody's task")
+	wantError[*apperrors.NoUserError](t, err)
+}
+
+func testOpenTaskRules(t *testing.T, b Backend) {
+	user := seedUsers(t, b, [2]string{"Ivanov", "Ivan"})[0]
+
+	// A user can run several tasks at once.
+	first, err := b.Tasks.StartTask(ctx, user.ID, "first")
+	must(t, err)
+	second, err := b.Tasks.StartTask(ctx, user.ID, "second")
+	must(t, err)
+	if first.ID == second.ID || second.EndTime != nil {
+		t.Fatalf("second running task = %+v", second)
+	}
+
+	_, err = b.Tasks.EndTask(ctx, first.ID, models.Precondition{Versions: []int{first.Version + 1}})
+	wantError[*apperrors.PreconditionFailedError](t, err)
+	ended, err := b.Tasks.EndTask(ctx, first.ID, models.Precondition{})
+	must(t, err)
+
+	_, err = b.Tasks.EndTask(ctx, first.ID, models.Precondition{})
+	wantError[*apperrors.TaskAlreadyEndedError](t, err)
+	_, err = b.Tasks.EndTask(ctx, first.ID, models.Precondition{Versions: []int{ended.Version}})
+	wantError[*apperrors.TaskAlreadyEndedError](t, err)
+	// The precondition is checked before whether the task has ended.
+	_, err = b.Tasks.EndTask(ctx, first.ID, models.Precondition{Versions: []int{first.Version}})
+	wantError[*apperrors.PreconditionFailedError](t, err)
+
+	got, err := b.Tasks.GetTaskByID(ctx, first.ID, false)
+	must(t, err)
+	if !got.EndTime.Equal(*ended.EndTime) || got.Version != ended.Version {
+		t.Fatalf("a failed end changed the task: %+v", got)
+	}
+	got, err = b.Tasks.GetTaskByID(ctx, second.ID, false)
+	must(t, err)
+	if got.EndTime != nil {
+		t.Fatalf("ending one task ended another: %+v", got)
+	}
+
+	_, err = b.Tasks.EndTask(ctx, second.ID+1000, models.Precondition{})
+	wantError[*apperrors.NoTaskError](t, err)
+}
+
+func testConcurrentEndTask(t *testing.T, b Backend) {
+	user := seedUsers(t, b, [2]string{"Ivanov", "Ivan"})[0]
+	task, err := b.Tasks.StartTask(ctx, user.ID, "contended")
+	must(t, err)
+
+	const n = 8
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = b.Tasks.EndTask(ctx, task.ID, models.Precondition{})
+		}(i)
+	}
+	wg.Wait()
+
+	ended := 0
+	for _, err := range errs {
+		if err == nil {
+			ended++
+			continue
+		}
+		wantError[*apperrors.TaskAlreadyEndedError](t, err)
+	}
+	if ended != 1 {
+		t.Fatalf("%d of %d concurrent ends succeeded, want 1", ended, n)
+	}
+	got, err := b.Tasks.GetTaskByID(ctx, task.ID, false)
+	must(t, err)
+	if got.Version != task.Version+1 {
+		t.Fatalf("version = %d, want %d", got.Version, task.Version+1)
+	}
+}
+
+func testTasksByPeriod(t *testing.T, b Backend) {
+	users := seedUsers(t, b, [2]string{"Ivanov", "Ivan"}, [2]string{"Petrov", "Petr"})
+	user, other := users[0], users[1]
+
+	before := time.Now().Add(-time.Minute)
+	short := runTask(t, b, user.ID, "short", 10*time.Millisecond)
+	long := runTask(t, b, user.ID, "long", 50*time.Millisecond)
+	medium := runTask(t, b, user.ID, "medium", 30*time.Millisecond)
+	runTask(t, b, other.ID, "someone else's", 20*time.Millisecond)
+	_, err := b.Tasks.StartTask(ctx, user.ID, "running")
+	must(t, err)
+	after := time.Now().Add(time.Minute)
+
+	tasks, err := b.Tasks.GetUserTasksByPeriod(ctx, user.ID, before, after, false)
+	must(t, err)
+	if want := []int{long.ID, medium.ID, short.ID}; !sameIDs(taskIDs(tasks), want) {
+		t.Fatalf("tasks = %v, want %v longest first", taskIDs(tasks), want)
+	}
+
+	// Both ends of the period are inclusive.
+	tasks, err = b.Tasks.GetUserTasksByPeriod(ctx, user.ID, long.StartTime, *long.EndTime, false)
+	must(t, err)
+	if want := []int{long.ID}; !sameIDs(taskIDs(tasks), want) {
+		t.Fatalf("tasks within the long one = %v, want %v", taskIDs(tasks), want)
+	}
+	// Tasks crossing a bound are left out.
+	tasks, err = b.Tasks.GetUserTasksByPeriod(ctx, user.ID, before, long.EndTime.Add(-time.Millisecond), false)
+	must(t, err)
+	if want := []int{short.ID}; !sameIDs(taskIDs(tasks), want) {
+		t.Fatalf("tasks until the long one ends = %v, want %v", taskIDs(tasks), want)
+	}
+	tasks, err = b.Tasks.GetUserTasksByPeriod(ctx, user.ID, after, after.Add(time.Hour), false)
+	must(t, err)
+	if len(tasks) != 0 {
+		t.Fatalf("tasks of an empty period = %v", taskIDs(tasks))
+	}
+
+	must(t, b.Users.DeleteUser(ctx, user.ID, models.Precondition{}))
+	tasks, err = b.Tasks.GetUserTasksByPeriod(ctx, user.ID, before, after, false)
+	must(t, err)
+	if len(tasks) != 0 {
+		t.Fatalf("tasks of a deleted user = %v", taskIDs(tasks))
+	}
+	tasks, err = b.Tasks.GetUserTasksByPeriod(ctx, user.ID, before, after, true)
+	must(t, err)
+	if len(tasks) != 3 {
+		t.Fatalf("tasks of a deleted user with deleted included = %v", taskIDs(tasks))
+	}
+}
+
+func testExportUser(t *testing.T, b Backend) {
+	user := seedUsers(t, b, [2]string{"Ivanov", "Ivan"})[0]
+	first := runTask(t, b, user.ID, "first", 0)
+	second, err := b.Tasks.StartTask(ctx, user.ID, "second")
+	must(t, err)
+
+	export, err := b.Users.ExportUser(ctx, user.ID)
+	must(t, err)
+	if export.User.ID != user.ID || export.User.PassportNumber != user.PassportNumber || export.ExportedAt.IsZero() {
+		t.Fatalf("export = %+v", export)
+	}
+	if want := []int{first.ID, second.ID}; !sameIDs(taskIDs(export.Tasks), want) {
+		t.Fatalf("exported tasks = %v, want %v", taskIDs(export.Tasks), want)
+	}
+
+	// Deleted users are exported with their tasks.
+	must(t, b.Users.DeleteUser(ctx, user.ID, models.Precondition{}))
+	export, err = b.Users.ExportUser(ctx, user.ID)
+	must(t, err)
+	if export.User.DeletedAt == nil || len(export.Tasks) != 2 || export.Tasks[0].DeletedAt == nil {
+		t.Fatalf("export of a deleted user = %+v", export)
+	}
+
+	_, err = b.Users.ExportUser(ctx, user.ID+1000)
+	wantError[*apperrors.NoUserError](t, err)
+}
+
+func testEraseUser(t *testing.T, b Backend) {
+	users := seedUsers(t, b, [2]string{"Ivanov", "Ivan"}, [2]string{"Petrov", "Petr"})
+	kept, removed := users[0], users[1]
+	keptTask := runTask(t, b, kept.ID, "private", 0)
+	runningTask, err := b.Tasks.StartTask(ctx, kept.ID, "running")
+	must(t, err)
+	removedTask := runTask(t, b, removed.ID, "private", 0)
+	if b.Idempotency != nil {
+		recordResponse(t, b, "ended", keptTask)
+		recordResponse(t, b, "running", runningTask)
+	}
+
+	record, err := b.Users.EraseUser(ctx, kept.ID, &models.EraseRequest{RequestedBy: "dpo", Reason: "request", RetainTimeRecords: true})
+	must(t, err)
+	if record.UserID != kept.ID || record.RequestedBy != "dpo" || record.Reason != "request" || !record.RetainedTimeRecords ||
+		record.TasksAffected != 2 || record.CreatedAt.IsZero() {
+		t.Fatalf("erasure record = %+v", record)
+	}
+	user, err := b.Users.GetUserByID(ctx, kept.ID, false)
+	must(t, err)
+	if user.PassportNumber != "" || user.Surname != "" || user.Name != "" || user.Address != "" || user.Version != kept.Version+1 {
+		t.Fatalf("erased user = %+v", user)
+	}
+	// Retained time records no longer refer to the user.
+	_, err = b.Tasks.GetTaskByID(ctx, keptTask.ID, true)
+	wantError[*apperrors.NoTaskError](t, err)
+	export, err := b.Users.ExportUser(ctx, kept.ID)
+	must(t, err)
+	if len(export.Tasks) != 0 {
+		t.Fatalf("tasks left after erasure: %+v", export.Tasks)
+	}
+	// Retries are not answered with the erased data.
+	if b.Idempotency != nil {
+		for _, key := range []string{"ended", "running"} {
+			stored, err := b.Idempotency.Reserve(ctx, "dpo", key, responseHash, time.Hour, time.Minute)
+			must(t, err)
+			if stored != nil && (bytes.Contains(stored.Body, []byte("private")) || bytes.Contains(stored.Body, []byte("running"))) {
+				t.Fatalf("response recorded for key %s after erasure: %s", key, stored.Body)
+			}
+		}
+	}
+
+	// Deleted users can be erased too.
+	must(t, b.Users.DeleteUser(ctx, removed.ID, models.Precondition{}))
+	record, err = b.Users.EraseUser(ctx, removed.ID, &models.EraseRequest{RequestedBy: "dpo"})
+	must(t, err)
+	if record.RetainedTimeRecords || record.TasksAffected != 1 {
+		t.Fatalf("erasure record = %+v", record)
+	}
+	_, err = b.Tasks.GetTaskByID(ctx, removedTask.ID, true)
+	wantError[*apperrors.NoTaskError](t, err)
+	user, err = b.Users.GetUserByID(ctx, removed.ID, true)
+	must(t, err)
+	if user.Surname != "" || user.DeletedAt == nil {
+		t.Fatalf("erased deleted user = %+v", user)
+	}
+
+	_, err = b.Users.EraseUser(ctx, removed.ID+1000, &models.EraseRequest{RequestedBy: "dpo"})
+	wantError[*apperrors.NoUserError](t, err)
+}
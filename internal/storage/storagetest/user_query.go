@@ -0,0 +1,195 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+)
+
+// userIDs returns the IDs of users in order.
+func userIDs(users []models.User) []int {
+	ids := make([]int, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func filter(field string, op models.FilterOp, values ...string) models.FieldFilter {
+	return models.FieldFilter{Field: field, Op: op, Values: values}
+}
+
+func testFilterUsers(t *testing.T, b Backend) {
+	users := seedUsers(t, b,
+		[2]string{"Ivanov", "Ivan"},
+		[2]string{"Petrov", "Petr"},
+		[2]string{"Sidorov", "Ivan"},
+		[2]string{"Ivanova", "Anna"},
+		[2]string{"Kuz_min", "100%"},
+	)
+	ivanov, petrov, sidorov, ivanova, kuzmin := users[0].ID, users[1].ID, users[2].ID, users[3].ID, users[4].ID
+	must(t, b.Users.DeleteUser(ctx, kuzmin, models.Precondition{}))
+	deleted := seedUsers(t, b, [2]string{"Deleted", "Ivan"})[0]
+	must(t, b.Users.DeleteUser(ctx, deleted.ID, models.Precondition{}))
+
+	tests := []struct {
+		name           string
+		filters        []models.FieldFilter
+		includeDeleted bool
+		want           []int
+	}{
+		{"no filters", nil, false, []int{ivanov, petrov, sidorov, ivanova}},
+		{"eq ignores case", []models.FieldFilter{filter("surname", models.OpEq, "IVANOV")}, false, []int{ivanov}},
+		{"ne", []models.FieldFilter{filter("name", models.OpNe, "ivan")}, false, []int{petrov, ivanova}},
+		{"contains", []models.FieldFilter{filter("surname", models.OpContains, "ANOV")}, false, []int{ivanov, ivanova}},
+		{"prefix", []models.FieldFilter{filter("surname", models.OpPrefix, "iv")}, false, []int{ivanov, ivanova}},
+		{"in", []models.FieldFilter{filter("name", models.OpIn, "anna", "PETR")}, false, []int{petrov, ivanova}},
+		{"and", []models.FieldFilter{filter("name", models.OpEq, "Ivan"), filter("surname", models.OpPrefix, "S")}, false, []int{sidorov}},
+		{"id in", []models.FieldFilter{filter("id", models.OpIn, itoa(petrov), itoa(sidorov), itoa(kuzmin))}, false, []int{petrov, sidorov}},
+		{"id gt", []models.FieldFilter{filter("id", models.OpGt, itoa(petrov))}, false, []int{sidorov, ivanova}},
+		{"id lte", []models.FieldFilter{filter("id", models.OpLte, itoa(petrov))}, false, []int{ivanov, petrov}},
+		{"snake case alias", []models.FieldFilter{filter("passport_number", models.OpEq, users[1].PassportNumber)}, false, []int{petrov}},
+		{"wildcards are literal", []models.FieldFilter{filter("surname", models.OpContains, "_")}, true, []int{kuzmin}},
+		{"percent is literal", []models.FieldFilter{filter("name", models.OpPrefix, "100%")}, true, []int{kuzmin}},
+		{"deleted included", []models.FieldFilter{filter("name", models.OpEq, "ivan")}, true, []int{ivanov, sidorov, deleted.ID}},
+		{"no match", []models.FieldFilter{filter("address", models.OpEq, "Kazan")}, false, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := b.Users.GetUsers(ctx, models.UserQuery{Filters: tt.filters, Limit: 10, IncludeDeleted: tt.includeDeleted})
+			must(t, err)
+			if got := userIDs(page.Users); !sameIDs(got, tt.want) || page.Total != len(tt.want) || page.Next != "" {
+				t.Fatalf("got users %v, total %d, next %q; want %v", got, page.Total, page.Next, tt.want)
+			}
+		})
+	}
+
+	// Timestamps compare at the precision they are stored with.
+	created := users[2].CreatedAt
+	page, err := b.Users.GetUsers(ctx, models.UserQuery{
+		Filters: []models.FieldFilter{filter("createdAt", models.OpGte, created.Add(-time.Second).Format(time.RFC3339))},
+		Limit:   10,
+	})
+	must(t, err)
+	if page.Total != 4 {
+		t.Fatalf("createdAt gte an earlier time matched %d users, want 4", page.Total)
+	}
+	page, err = b.Users.GetUsers(ctx, models.UserQuery{
+		Filters: []models.FieldFilter{filter("createdAt", models.OpGt, created.Add(time.Hour).Format(time.RFC3339))},
+		Limit:   10,
+	})
+	must(t, err)
+	if page.Total != 0 {
+		t.Fatalf("createdAt gt a later time matched %d users, want 0", page.Total)
+	}
+}
+
+func testInvalidUserQueries(t *testing.T, b Backend) {
+	seedUsers(t, b, [2]string{"Ivanov", "Ivan"}, [2]string{"Petrov", "Petr"})
+	page, err := b.Users.GetUsers(ctx, models.UserQuery{Sort: []models.SortField{{Field: "surname"}}, Limit: 1})
+	must(t, err)
+	if page.Next == "" {
+		t.Fatal("first of two pages has no cursor")
+	}
+
+	queries := map[string]models.UserQuery{
+		"unknown filter field":    {Filters: []models.FieldFilter{filter("password", models.OpEq, "x")}},
+		"unsupported operator":    {Filters: []models.FieldFilter{filter("createdAt", models.OpContains, "2024")}},
+		"unknown operator":        {Filters: []models.FieldFilter{filter("name", "like", "x")}},
+		"invalid int":             {Filters: []models.FieldFilter{filter("id", models.OpEq, "one")}},
+		"invalid time":            {Filters: []models.FieldFilter{filter("createdAt", models.OpGt, "yesterday")}},
+		"no values":               {Filters: []models.FieldFilter{filter("name", models.OpEq)}},
+		"several values":          {Filters: []models.FieldFilter{filter("name", models.OpEq, "a", "b")}},
+		"unknown sort field":      {Sort: []models.SortField{{Field: "password"}}},
+		"garbage cursor":          {Cursor: "not a cursor"},
+		"cursor of another order": {Sort: []models.SortField{{Field: "name"}}, Cursor: page.Next},
+	}
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			query.Limit = 10
+			_, err := b.Users.GetUsers(ctx, query)
+			wantError[*apperrors.BadRequestError](t, err)
+		})
+	}
+}
+
+func testPageUsers(t *testing.T, b Backend) {
+	users := seedUsers(t, b,
+		[2]string{"Alekseev", "Oleg"},
+		[2]string{"Borisov", "Ivan"},
+		[2]string{"Vasiliev", "Ivan"},
+		[2]string{"Grigoriev", "Anna"},
+		[2]string{"Dmitriev", "Ivan"},
+		[2]string{"Egorov", "Boris"},
+		[2]string{"Zaitsev", "Anna"},
+	)
+	id := func(i int) int { return users[i].ID }
+
+	orders := []struct {
+		name string
+		sort []models.SortField
+		want []int
+	}{
+		{"default", nil, []int{id(0), id(1), id(2), id(3), id(4), id(5), id(6)}},
+		{"surname", []models.SortField{{Field: "surname"}}, []int{id(0), id(1), id(4), id(5), id(3), id(2), id(6)}},
+		{"surname descending", []models.SortField{{Field: "surname", Desc: true}}, []int{id(6), id(2), id(3), id(5), id(4), id(1), id(0)}},
+		// Ties are broken by the id.
+		{"name", []models.SortField{{Field: "name"}}, []int{id(3), id(6), id(5), id(1), id(2), id(4), id(0)}},
+		{"name descending, id descending", []models.SortField{{Field: "name", Desc: true}, {Field: "id", Desc: true}}, []int{id(0), id(4), id(2), id(1), id(5), id(6), id(3)}},
+		{"createdAt descending", []models.SortField{{Field: "createdAt", Desc: true}}, nil},
+	}
+	for _, order := range orders {
+		t.Run(order.name, func(t *testing.T) {
+			full, err := b.Users.GetUsers(ctx, models.UserQuery{Sort: order.sort, Limit: 100})
+			must(t, err)
+			if order.want != nil && !sameIDs(userIDs(full.Users), order.want) {
+				t.Fatalf("order = %v, want %v", userIDs(full.Users), order.want)
+			}
+
+			var paged []int
+			query := models.UserQuery{Sort: order.sort, Limit: 3}
+			for pages := 0; ; pages++ {
+				if pages > len(users) {
+					t.Fatal("paging does not end")
+				}
+				page, err := b.Users.GetUsers(ctx, query)
+				must(t, err)
+				if page.Total != len(users) {
+					t.Fatalf("total = %d, want %d", page.Total, len(users))
+				}
+				if len(page.Users) > query.Limit {
+					t.Fatalf("page of %d users, limit %d", len(page.Users), query.Limit)
+				}
+				paged = append(paged, userIDs(page.Users)...)
+				if page.Next == "" {
+					break
+				}
+				query.Cursor = page.Next
+			}
+			if !sameIDs(paged, userIDs(full.Users)) {
+				t.Fatalf("pages = %v, want %v", paged, userIDs(full.Users))
+			}
+		})
+	}
+
+	// A page that ends exactly with the last user has no cursor.
+	page, err := b.Users.GetUsers(ctx, models.UserQuery{Limit: len(users)})
+	must(t, err)
+	if len(page.Users) != len(users) || page.Next != "" {
+		t.Fatalf("full page has %d users and cursor %q", len(page.Users), page.Next)
+	}
+}
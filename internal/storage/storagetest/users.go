@@ -0,0 +1,184 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+)
+
+var ctx = context.Background()
+
+// seedUsers creates users with the given surname and name pairs.
+func seedUsers(t *testing.T, b Backend, names ...[2]string) []models.User {
+	t.Helper()
+	users := make([]models.User, 0, len(names))
+	for i, n := range names {
+		user := models.User{
+			PassportNumber: fmt.Sprintf("1234 %06d", i),
+			Surname:        n[0],
+			Name:           n[1],
+			Address:        "Moscow",
+		}
+		must(t, b.Users.CreateUser(ctx, &user))
+		users = append(users, user)
+	}
+	return users
+}
+
+func testUserLifecycle(t *testing.T, b Backend) {
+	user := models.User{PassportNumber: "1234 567890", Surname: "Ivanov", Name: "Ivan", Patronymic: "Ivanovich", Address: "Moscow"}
+	must(t, b.Users.CreateUser(ctx, &user))
+	if user.ID <= 0 || user.Version != 1 || user.CreatedAt.IsZero() || !user.UpdatedAt.Equal(user.CreatedAt) || user.DeletedAt != nil {
+		t.Fatalf("created user = %+v", user)
+	}
+
+	got, err := b.Users.GetUserByID(ctx, user.ID, false)
+	must(t, err)
+	if got.PassportNumber != user.PassportNumber || got.Surname != "Ivanov" || got.Patronymic != "Ivanovich" || got.Version != 1 || !got.CreatedAt.Equal(user.CreatedAt) {
+		t.Fatalf("GetUserByID = %+v, want %+v", got, user)
+	}
+
+	update := models.User{ID: user.ID, PassportNumber: "4321 098765", Surname: "Petrov", Name: "Petr", Address: "Kazan"}
+	must(t, b.Users.UpdateUser(ctx, &update, models.Precondition{}))
+	if update.Version != 2 || update.Surname != "Petrov" || update.Patronymic != "" || !update.CreatedAt.Equal(user.CreatedAt) || update.UpdatedAt.Before(user.UpdatedAt) {
+		t.Fatalf("updated user = %+v", update)
+	}
+	got, err = b.Users.GetUserByID(ctx, user.ID, false)
+	must(t, err)
+	if got.PassportNumber != "4321 098765" || got.Address != "Kazan" || got.Version != 2 {
+		t.Fatalf("GetUserByID after update = %+v", got)
+	}
+
+	_, err = b.Users.GetUserByID(ctx, user.ID+1000, false)
+	wantError[*apperrors.NoUserError](t, err)
+	missing := models.User{ID: user.ID + 1000, PassportNumber: "1234 567890"}
+	wantError[*apperrors.NoUserError](t, b.Users.UpdateUser(ctx, &missing, models.Precondition{}))
+}
+
+func testUserPreconditions(t *testing.T, b Backend) {
+	user := seedUsers(t, b, [2]string{"Ivanov", "Ivan"})[0]
+
+	stale := models.Precondition{Versions: []int{user.Version + 1}}
+	update := user
+	update.Name = "Petr"
+	wantError[*apperrors.PreconditionFailedError](t, b.Users.UpdateUser(ctx, &update, stale))
+	wantError[*apperrors.PreconditionFailedError](t, b.Users.DeleteUser(ctx, user.ID, stale))
+
+	got, err := b.Users.GetUserByID(ctx, user.ID, false)
+	must(t, err)
+	if got.Name != "Ivan" || got.Version != user.Version {
+		t.Fatalf("a failed precondition changed the user: %+v", got)
+	}
+
+	// Any listed version matches.
+	must(t, b.Users.UpdateUser(ctx, &update, models.Precondition{Versions: []int{7, user.Version}}))
+	if update.Version != user.Version+1 {
+		t.Fatalf("version = %d, want %d", update.Version, user.Version+1)
+	}
+}
+
+func testPatchUser(t *testing.T, b Backend) {
+	user := seedUsers(t, b, [2]string{"Ivanov", "Ivan"})[0]
+
+	errApply := errors.New("rejected")
+	_, err := b.Users.PatchUser(ctx, user.ID, models.Precondition{}, func(u *models.User) error {
+		u.Name = "Changed"
+		return errApply
+	})
+	if !errors.Is(err, errApply) {
+		t.Fatalf("PatchUser returned %v, want the error of apply", err)
+	}
+
+	patched, err := b.Users.PatchUser(ctx, user.ID, models.Precondition{Versions: []int{user.Version}}, func(u *models.User) error {
+		if u.Name != "Ivan" || u.Version != user.Version {
+			t.Errorf("apply got %+v, want the current user", u)
+		}
+		u.Address = "Kazan"
+		// Only personal data is writable.
+		u.Version = 100
+		u.ID = 100
+		return nil
+	})
+	must(t, err)
+	if patched.ID != user.ID || patched.Address != "Kazan" || patched.Name != "Ivan" || patched.Version != user.Version+1 {
+		t.Fatalf("patched user = %+v", patched)
+	}
+
+	_, err = b.Users.PatchUser(ctx, user.ID, models.Precondition{Versions: []int{user.Version}}, func(*models.User) error { return nil })
+	wantError[*apperrors.PreconditionFailedError](t, err)
+	_, err = b.Users.PatchUser(ctx, user.ID+1000, models.Precondition{}, func(*models.User) error { return nil })
+	wantError[*apperrors.NoUserError](t, err)
+}
+
+func testDeleteAndRestoreUser(t *testing.T, b Backend) {
+	users := seedUsers(t, b, [2]string{"Ivanov", "Ivan"}, [2]string{"Petrov", "Petr"})
+	user := users[0]
+
+	ended, err := b.Tasks.StartTask(ctx, user.ID, "ended")
+	must(t, err)
+	ended, err = b.Tasks.EndTask(ctx, ended.ID, models.Precondition{})
+	must(t, err)
+	running, err := b.Tasks.StartTask(ctx, user.ID, "running")
+	must(t, err)
+
+	must(t, b.Users.DeleteUser(ctx, user.ID, models.Precondition{Versions: []int{user.Version}}))
+	_, err = b.Users.GetUserByID(ctx, user.ID, false)
+	wantError[*apperrors.NoUserError](t, err)
+	deleted, err := b.Users.GetUserByID(ctx, user.ID, true)
+	must(t, err)
+	if deleted.DeletedAt == nil || deleted.Version != user.Version+1 {
+		t.Fatalf("deleted user = %+v", deleted)
+	}
+	_, err = b.Tasks.GetTaskByID(ctx, running.ID, false)
+	wantError[*apperrors.NoTaskError](t, err)
+	task, err := b.Tasks.GetTaskByID(ctx, running.ID, true)
+	must(t, err)
+	if task.DeletedAt == nil || !task.DeletedAt.Equal(*deleted.DeletedAt) || task.Version != running.Version+1 {
+		t.Fatalf("task of a deleted user = %+v", task)
+	}
+
+	// A deleted user can't be changed or deleted again.
+	update := user
+	wantError[*apperrors.NoUserError](t, b.Users.UpdateUser(ctx, &update, models.Precondition{}))
+	wantError[*apperrors.NoUserError](t, b.Users.DeleteUser(ctx, user.ID, models.Precondition{}))
+	_, err = b.Tasks.StartTask(ctx, user.ID, "after deletion")
+	wantError[*apperrors.NoUserError](t, err)
+	_, err = b.Tasks.EndTask(ctx, running.ID, models.Precondition{})
+	wantError[*apperrors.NoTaskError](t, err)
+
+	page, err := b.Users.GetUsers(ctx, models.UserQuery{Limit: 10})
+	must(t, err)
+	if page.Total != 1 || len(page.Users) != 1 || page.Users[0].ID != users[1].ID {
+		t.Fatalf("users without deleted = %+v", page)
+	}
+	page, err = b.Users.GetUsers(ctx, models.UserQuery{Limit: 10, IncludeDeleted: true})
+	must(t, err)
+	if page.Total != 2 {
+		t.Fatalf("users with deleted = %+v", page)
+	}
+
+	restored, err := b.Users.RestoreUser(ctx, user.ID)
+	must(t, err)
+	if restored.DeletedAt != nil || restored.Version != deleted.Version+1 {
+		t.Fatalf("restored user = %+v", restored)
+	}
+	for _, before := range []*models.Task{ended, running} {
+		task, err := b.Tasks.GetTaskByID(ctx, before.ID, false)
+		must(t, err)
+		if task.DeletedAt != nil || task.Version != before.Version+2 {
+			t.Fatalf("restored task = %+v", task)
+		}
+	}
+
+	again, err := b.Users.RestoreUser(ctx, user.ID)
+	must(t, err)
+	if again.Version != restored.Version {
+		t.Fatalf("restoring a user that is not deleted changed it: %+v", again)
+	}
+	_, err = b.Users.RestoreUser(ctx, user.ID+1000)
+	wantError[*apperrors.NoUserError](t, err)
+}
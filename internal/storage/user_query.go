@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"time-tracker/internal/apperrors"
+	"time-tracker/internal/models"
+)
+
+type FieldKind int
+
+const (
+	TextField FieldKind = iota
+	IntField
+	TimeField
+)
+
+// UserFields are the fields users can be filtered and ordered by. Text
+// fields compare case-insensitively in filters.
+var UserFields = map[string]FieldKind{
+	"id":             IntField,
+	"passportNumber": TextField,
+	"surname":        TextField,
+	"name":           TextField,
+	"patronymic":     TextField,
+	"address":        TextField,
+	"createdAt":      TimeField,
+	"updatedAt":      TimeField,
+}
+
+// userFieldAliases keeps the snake_case filter names of the first API version working.
+var userFieldAliases = map[string]string{
+	"passport_number": "passportNumber",
+	"created_at":      "createdAt",
+	"updated_at":      "updatedAt",
+}
+
+var allowedOps = map[FieldKind][]models.FilterOp{
+	TextField: {models.OpEq, models.OpNe, models.OpContains, models.OpPrefix, models.OpIn},
+	IntField:  {models.OpEq, models.OpNe, models.OpIn, models.OpGt, models.OpGte, models.OpLt, models.OpLte},
+	TimeField: {models.OpEq, models.OpGt, models.OpGte, models.OpLt, models.OpLte},
+}
+
+// Filter is a checked filter of a user query. Values hold an int, a
+// time.Time or a string depending on the kind of the field.
+type Filter struct {
+	Field  string
+	Kind   FieldKind
+	Op     models.FilterOp
+	Values []interface{}
+}
+
+type SortKey struct {
+	Field string
+	Kind  FieldKind
+	Desc  bool
+}
+
+// UserListing is a user query checked and decoded for a backend to run.
+type UserListing struct {
+	Filters []Filter
+	// Keys always end with the id, so that every user has a distinct
+	// position.
+	Keys []SortKey
+	// After holds the key values of the last user of the previous page, in
+	// the same types as filter values. It is nil on the first page.
+	After []interface{}
+}
+
+// ResolveUserQuery checks the fields, operators and values of a query and
+// decodes its cursor.
+func ResolveUserQuery(query models.UserQuery) (*UserListing, error) {
+	listing := &UserListing{}
+	for _, filter := range query.Filters {
+		f, err := resolveFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		listing.Filters = append(listing.Filters, f)
+	}
+
+	hasID := false
+	for _, s := range query.Sort {
+		name, kind, err := resolveUserField(s.Field)
+		if err != nil {
+			return nil, err
+		}
+		listing.Keys = append(listing.Keys, SortKey{Field: name, Kind: kind, Desc: s.Desc})
+		hasID = hasID || name == "id"
+	}
+	if !hasID {
+		listing.Keys = append(listing.Keys, SortKey{Field: "id", Kind: IntField})
+	}
+
+	if query.Cursor != "" {
+		after, err := decodeUserCursor(listing.Keys, query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		listing.After = after
+	}
+	return listing, nil
+}
+
+func resolveUserField(name string) (string, FieldKind, error) {
+	if alias, ok := userFieldAliases[name]; ok {
+		name = alias
+	}
+	kind, ok := UserFields[name]
+	if !ok {
+		return "", 0, &apperrors.BadRequestError{Message: fmt.Sprintf("Unknown field %q", name)}
+	}
+	return name, kind, nil
+}
+
+func resolveFilter(filter models.FieldFilter) (Filter, error) {
+	name, kind, err := resolveUserField(filter.Field)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	allowed := false
+	for _, op := range allowedOps[kind] {
+		allowed = allowed || op == filter.Op
+	}
+	if !allowed {
+		return Filter{}, &apperrors.BadRequestError{Message: fmt.Sprintf("Operator %q is not supported for field %q", filter.Op, name)}
+	}
+	if len(filter.Values) == 0 || (filter.Op != models.OpIn && len(filter.Values) > 1) {
+		return Filter{}, &apperrors.BadRequestError{Message: fmt.Sprintf("Invalid number of values for field %q", name)}
+	}
+
+	f := Filter{Field: name, Kind: kind, Op: filter.Op, Values: make([]interface{}, 0, len(filter.Values))}
+	for _, raw := range filter.Values {
+		val, err := convertValue(kind, raw)
+		if err != nil {
+			return Filter{}, &apperrors.BadRequestError{Message: fmt.Sprintf("Invalid value %q for field %q", raw, name)}
+		}
+		f.Values = append(f.Values, val)
+	}
+	return f, nil
+}
+
+func convertValue(kind FieldKind, raw string) (interface{}, error) {
+	switch kind {
+	case IntField:
+		return strconv.Atoi(raw)
+	case TimeField:
+		return time.Parse(time.RFC3339, raw)
+	default:
+		return raw, nil
+	}
+}
+
+// UserValue returns a field of a user in the type of filter values.
+func UserValue(user *models.User, name string) interface{} {
+	switch name {
+	case "id":
+		return user.ID
+	case "passportNumber":
+		return user.PassportNumber
+	case "surname":
+		return user.Surname
+	case "name":
+		return user.Name
+	case "patronymic":
+		return user.Patronymic
+	case "address":
+		return user.Address
+	case "createdAt":
+		return user.CreatedAt
+	case "updatedAt":
+		return user.UpdatedAt
+	}
+	return nil
+}
+
+func sortSpec(keys []SortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Desc {
+			parts = append(parts, "-"+key.Field)
+		} else {
+			parts = append(parts, key.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// userCursor is the decoded form of the opaque "next" token: the sort
+// specification it was issued for and the sort key values of the last row.
+type userCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// EncodeUserCursor returns the cursor of the page that follows user.
+func EncodeUserCursor(keys []SortKey, user *models.User) string {
+	cursor := userCursor{Sort: sortSpec(keys)}
+	for _, key := range keys {
+		val := UserValue(user, key.Field)
+		if t, ok := val.(time.Time); ok {
+			val = t.Format(time.RFC3339Nano)
+		}
+		cursor.Values = append(cursor.Values, val)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(keys []SortKey, token string) ([]interface{}, error) {
+	invalid := &apperrors.BadRequestError{Message: "Invalid cursor"}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor userCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || len(cursor.Values) != len(keys) {
+		return nil, invalid
+	}
+	if cursor.Sort != sortSpec(keys) {
+		return nil, &apperrors.BadRequestError{Message: "Cursor was issued for a different sort order"}
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		switch raw := cursor.Values[i].(type) {
+		case string:
+			switch key.Kind {
+			case IntField:
+				return nil, invalid
+			case TimeField:
+				t, err := time.Parse(time.RFC3339Nano, raw)
+				if err != nil {
+					return nil, invalid
+				}
+				values[i] = t
+			default:
+				values[i] = raw
+			}
+		case float64:
+			if key.Kind != IntField {
+				return nil, invalid
+			}
+			values[i] = int(raw)
+		default:
+			return nil, invalid
+		}
+	}
+	return values, nil
+}
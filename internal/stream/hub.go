@@ -0,0 +1,266 @@
+// Package stream pushes live timer updates to SSE and WebSocket clients.
+// Domain events are announced with NOTIFY by the transaction that records
+// them, so a client sees the tasks started and ended through any instance.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"time-tracker/internal/config"
+	db "time-tracker/internal/database"
+	"time-tracker/internal/logger"
+	"time-tracker/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// subscriberBuffer is how many events a client may lag behind before it
+	// is disconnected.
+	subscriberBuffer = 64
+
+	maxReconnectDelay = 30 * time.Second
+
+	// resync asks the sessions to reload their snapshot after the listener
+	// reconnected and notifications may have been missed.
+	resync = "resync"
+)
+
+// Hub listens for domain events on a dedicated database connection and fans
+// the ones about tasks out to the connected clients.
+type Hub struct {
+	pool  *pgxpool.Pool
+	tasks *db.TaskRepository
+	cfg   config.StreamConfig
+
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+	status HubStatus
+}
+
+// HubStatus describes the hub for /debug/status.
+type HubStatus struct {
+	Name       string `json:"name"`
+	Running    bool   `json:"running"`
+	Listening  bool   `json:"listening"`
+	Clients    int    `json:"clients"`
+	Reconnects int64  `json:"reconnects"`
+	Dropped    int64  `json:"dropped"`
+	LastError  string `json:"lastError,omitempty"`
+}
+
+// event is a domain event relevant to stream clients.
+type event struct {
+	id     string
+	typ    string
+	at     time.Time
+	userID int
+	task   *models.Task
+}
+
+type subscription struct {
+	// users is empty when every user is followed.
+	users  map[int]bool
+	events chan event
+}
+
+func (s *subscription) follows(userID int) bool {
+	return len(s.users) == 0 || s.users[userID]
+}
+
+func NewHub(pool *pgxpool.Pool, tasks *db.TaskRepository, cfg config.StreamConfig) *Hub {
+	return &Hub{
+		pool:   pool,
+		tasks:  tasks,
+		cfg:    cfg,
+		subs:   make(map[*subscription]struct{}),
+		status: HubStatus{Name: "stream"},
+	}
+}
+
+func (h *Hub) Status() HubStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+func (h *Hub) update(fn func(s *HubStatus)) {
+	h.mu.Lock()
+	fn(&h.status)
+	h.mu.Unlock()
+}
+
+// Run listens for events until ctx is cancelled, reconnecting with backoff
+// when the connection is lost. On return every client is disconnected so
+// that the HTTP server can shut down.
+func (h *Hub) Run(ctx context.Context) {
+	logger.FromContext(ctx).Info("Stream hub started",
+		"channel", db.EventsChannel,
+		"tickInterval", h.cfg.TickInterval,
+	)
+
+	h.update(func(s *HubStatus) { s.Running = true })
+	defer h.shutdown()
+
+	first := true
+	delay := time.Second
+	for {
+		err := h.listen(ctx, func() {
+			h.update(func(s *HubStatus) { s.Listening = true })
+			if !first {
+				h.update(func(s *HubStatus) { s.Reconnects++ })
+				h.broadcast(event{typ: resync})
+			}
+			first = false
+			delay = time.Second
+		})
+		h.update(func(s *HubStatus) {
+			s.Listening = false
+			s.LastError = err.Error()
+		})
+		if ctx.Err() != nil {
+			logger.FromContext(ctx).Info("Stream hub stopped")
+			return
+		}
+
+		logger.FromContext(ctx).Warn("Lost the event listener connection, reconnecting",
+			"error", err,
+			"retryIn", delay,
+		)
+		select {
+		case <-ctx.Done():
+			logger.FromContext(ctx).Info("Stream hub stopped")
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
+
+// listen holds a connection in LISTEN and broadcasts the notifications it
+// receives. listening is called once the channel is subscribed.
+func (h *Hub) listen(ctx context.Context, listening func()) error {
+	pooled, err := h.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection leaves the pool for good: it is busy waiting for
+	// notifications and its LISTEN must not leak to other queries.
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{db.EventsChannel}.Sanitize()); err != nil {
+		return err
+	}
+	listening()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if e, ok := decode(notification.Payload); ok {
+			h.broadcast(e)
+		}
+	}
+}
+
+// decode parses a notification payload, skipping the events clients don't
+// care about.
+func decode(payload string) (event, bool) {
+	var envelope struct {
+		ID         string          `json:"id"`
+		Type       string          `json:"type"`
+		OccurredAt time.Time       `json:"occurredAt"`
+		Data       json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+		return event{}, false
+	}
+
+	e := event{id: envelope.ID, typ: envelope.Type, at: envelope.OccurredAt}
+	switch envelope.Type {
+	case models.EventTaskStarted, models.EventTaskEnded:
+		var task models.Task
+		if err := json.Unmarshal(envelope.Data, &task); err != nil {
+			return event{}, false
+		}
+		e.task, e.userID = &task, task.UserID
+	case models.EventUserDeleted:
+		// The tasks of a deleted user are deleted along with them.
+		var user models.User
+		if err := json.Unmarshal(envelope.Data, &user); err != nil {
+			return event{}, false
+		}
+		e.userID = user.ID
+	default:
+		return event{}, false
+	}
+	return e, true
+}
+
+func (h *Hub) broadcast(e event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if e.typ != resync && !sub.follows(e.userID) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			// A client that can't keep up is disconnected; it starts over
+			// from a fresh snapshot when it reconnects.
+			delete(h.subs, sub)
+			close(sub.events)
+			h.status.Dropped++
+		}
+	}
+	h.status.Clients = len(h.subs)
+}
+
+func (h *Hub) subscribe(users []int) *subscription {
+	sub := &subscription{users: make(map[int]bool, len(users)), events: make(chan event, subscriberBuffer)}
+	for _, id := range users {
+		sub.users[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.events)
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+	h.status.Clients = len(h.subs)
+	return sub
+}
+
+func (h *Hub) unsubscribe(sub *subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+	h.status.Clients = len(h.subs)
+}
+
+func (h *Hub) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		close(sub.events)
+	}
+	h.subs = make(map[*subscription]struct{})
+	h.closed = true
+	h.status.Running = false
+	h.status.Listening = false
+	h.status.Clients = 0
+}
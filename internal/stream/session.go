@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"time-tracker/internal/models"
+)
+
+// Session is the state of one client: the users it follows and their
+// running tasks.
+type Session struct {
+	hub    *Hub
+	sub    *subscription
+	users  []int
+	timers map[int]models.Task
+}
+
+// Open follows the given users, or every user when users is empty, and loads
+// their running tasks. The subscription is taken before the tasks are read
+// so that no event committed after the snapshot is missed.
+func (h *Hub) Open(ctx context.Context, users []int) (*Session, error) {
+	s := &Session{hub: h, sub: h.subscribe(users), users: users}
+	if err := s.load(ctx); err != nil {
+		h.unsubscribe(s.sub)
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close releases the subscription of the session.
+func (s *Session) Close() {
+	s.hub.unsubscribe(s.sub)
+}
+
+// Run sends the snapshot and then every event and tick until ctx is
+// cancelled, send fails, or the hub disconnects the client because it shuts
+// down or the client fell behind.
+func (s *Session) Run(ctx context.Context, send func(msg models.StreamMessage) error) error {
+	if err := send(s.snapshot(time.Now())); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.hub.cfg.TickInterval)
+	defer ticker.Stop()
+
+	for {
+		var msg models.StreamMessage
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			msg = models.StreamMessage{Type: models.StreamTick, At: now.UTC(), Timers: s.elapsed(now)}
+		case e, ok := <-s.sub.events:
+			if !ok {
+				return nil
+			}
+			if e.typ == resync {
+				if err := s.load(ctx); err != nil {
+					return err
+				}
+				msg = s.snapshot(time.Now())
+				break
+			}
+			msg = s.apply(e)
+		}
+		if err := send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Session) load(ctx context.Context) error {
+	tasks, err := s.hub.tasks.GetRunningTasks(ctx, s.users)
+	if err != nil {
+		return err
+	}
+	s.timers = make(map[int]models.Task, len(tasks))
+	for _, task := range tasks {
+		s.timers[task.ID] = task
+	}
+	return nil
+}
+
+// apply updates the running tasks with an event. Events may repeat what the
+// snapshot already shows, so every change is idempotent.
+func (s *Session) apply(e event) models.StreamMessage {
+	switch e.typ {
+	case models.EventTaskStarted:
+		s.timers[e.task.ID] = *e.task
+	case models.EventTaskEnded:
+		delete(s.timers, e.task.ID)
+	case models.EventUserDeleted:
+		for id, task := range s.timers {
+			if task.UserID == e.userID {
+				delete(s.timers, id)
+			}
+		}
+	}
+	return models.StreamMessage{Type: e.typ, EventID: e.id, At: e.at, Task: e.task, UserID: e.userID}
+}
+
+func (s *Session) snapshot(now time.Time) models.StreamMessage {
+	return models.StreamMessage{Type: models.StreamSnapshot, At: now.UTC(), Timers: s.elapsed(now)}
+}
+
+// elapsed lists the running tasks, longest running first.
+func (s *Session) elapsed(now time.Time) []models.Timer {
+	timers := make([]models.Timer, 0, len(s.timers))
+	for _, task := range s.timers {
+		timers = append(timers, models.Timer{
+			TaskID:         task.ID,
+			UserID:         task.UserID,
+			Description:    task.Description,
+			StartTime:      task.StartTime,
+			ElapsedSeconds: int64(now.Sub(task.StartTime) / time.Second),
+		})
+	}
+	sort.Slice(timers, func(i, j int) bool {
+		if !timers[i].StartTime.Equal(timers[j].StartTime) {
+			return timers[i].StartTime.Before(timers[j].StartTime)
+		}
+		return timers[i].TaskID < timers[j].TaskID
+	})
+	return timers
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const maxStatementLength = 2048
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^$\w.])\d+(?:\.\d+)?\b`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL replaces literals with ? so that data embedded in a statement
+// never ends up in a span; positional parameters are kept as they are.
+func SanitizeSQL(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = numericLiteral.ReplaceAllString(sql, "${1}?")
+	sql = strings.TrimSpace(whitespace.ReplaceAllString(sql, " "))
+	if len(sql) > maxStatementLength {
+		sql = sql[:maxStatementLength] + "..."
+	}
+	return sql
+}
+
+// PgxTracer creates a client span for every query run through pgx.
+type PgxTracer struct {
+	DatabaseName string
+}
+
+func (t *PgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		// Don't start root spans for background work such as pool health checks.
+		return ctx
+	}
+	ctx, _ = tracer().Start(ctx, "db "+operation(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBName(t.DatabaseName),
+			semconv.DBStatement(SanitizeSQL(data.SQL)),
+		),
+	)
+	return ctx
+}
+
+func (t *PgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,127 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+type Lang string
+
+const (
+	English Lang = "en"
+	Russian Lang = "ru"
+)
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Russian})
+
+// Language picks the best supported language for an Accept-Language header,
+// English by default.
+func Language(acceptLanguage string) Lang {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return English
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return English
+	}
+	if index == 1 {
+		return Russian
+	}
+	return English
+}
+
+// messages maps a rule, or a rule with a kind suffix, to its message
+// template. %[1]s is the rule parameter.
+var messages = map[Lang]map[string]string{
+	English: {
+		"invalid":      "Invalid request data",
+		"malformed":    "Request body is not valid JSON",
+		"type":         "has the wrong type",
+		"unknownField": "unknown or read-only field",
+		"string":       "must be a string",
+
+		"required":     "is required",
+		"notblank":     "must not be blank",
+		"passport":     "must be a four digit series and a six digit number separated by a space",
+		"rfc3339":      "must be a date and time in RFC3339 format, e.g. 2024-01-31T09:00:00Z",
+		"rfc3339after": "must be later than %[1]s",
+		"oneof":        "must be one of: %[1]s",
+		"url":          "must be an absolute URL",
+		"startswith":   "must start with %[1]s",
+		"max.string":   "must be at most %[1]s characters long",
+		"min.string":   "must be at least %[1]s characters long",
+		"max":          "must be at most %[1]s",
+		"min":          "must be at least %[1]s",
+		"gt":           "must be greater than %[1]s",
+		"gte":          "must be greater than or equal to %[1]s",
+		"lt":           "must be less than %[1]s",
+		"lte":          "must be less than or equal to %[1]s",
+		"default":      "is invalid",
+	},
+	Russian: {
+		"invalid":      "Некорректные данные запроса",
+		"malformed":    "Тело запроса не является корректным JSON",
+		"type":         "имеет неверный тип",
+		"unknownField": "неизвестное поле или поле только для чтения",
+		"string":       "должно быть строкой",
+
+		"required":     "обязательное поле",
+		"notblank":     "не может быть пустым",
+		"passport":     "должен состоять из четырёхзначной серии и шестизначного номера через пробел",
+		"rfc3339":      "должно быть датой и временем в формате RFC3339, например 2024-01-31T09:00:00Z",
+		"rfc3339after": "должно быть позже, чем %[1]s",
+		"oneof":        "должно быть одним из: %[1]s",
+		"url":          "должно быть абсолютным URL",
+		"startswith":   "должно начинаться с %[1]s",
+		"max.string":   "должно содержать не более %[1]s символов",
+		"min.string":   "должно содержать не менее %[1]s символов",
+		"max":          "должно быть не больше %[1]s",
+		"min":          "должно быть не меньше %[1]s",
+		"gt":           "должно быть больше %[1]s",
+		"gte":          "должно быть не меньше %[1]s",
+		"lt":           "должно быть меньше %[1]s",
+		"lte":          "должно быть не больше %[1]s",
+		"default":      "некорректное значение",
+	},
+}
+
+// Message returns the text for key in lang.
+func Message(lang Lang, key string, args ...interface{}) string {
+	catalog, ok := messages[lang]
+	if !ok {
+		catalog = messages[English]
+	}
+	tmpl, ok := catalog[key]
+	if !ok {
+		tmpl = catalog["default"]
+	}
+	if len(args) == 0 {
+		return tmpl
+	}
+	return fmt.Sprintf(tmpl, args...)
+}
+
+func fieldMessage(lang Lang, fe validator.FieldError) string {
+	key := fe.Tag()
+	if fe.Kind() == reflect.String {
+		if _, ok := messages[English][key+".string"]; ok {
+			key += ".string"
+		}
+	}
+	param := fe.Param()
+	if fe.Tag() == "rfc3339after" {
+		param = strings.ToLower(param[:1]) + param[1:]
+	}
+	if _, ok := messages[English][key]; !ok {
+		return Message(lang, "default")
+	}
+	if strings.Contains(messages[English][key], "%[1]s") {
+		return Message(lang, key, param)
+	}
+	return Message(lang, key)
+}
@@ -0,0 +1,117 @@
+// Package validation registers the custom binding rules on gin's validator
+// and turns validation failures into localized apperrors.ValidationError.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"time-tracker/internal/apperrors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var passportPattern = regexp.MustCompile(`^\d{4} \d{6}$`)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		panic("validation: unexpected gin validator engine")
+	}
+
+	// Report fields under the names clients use.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+
+	must := func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	}
+	must(v.RegisterValidation("passport", func(fl validator.FieldLevel) bool {
+		return ValidPassport(fl.Field().String())
+	}))
+	must(v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	}))
+	must(v.RegisterValidation("rfc3339", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(time.RFC3339, fl.Field().String())
+		return err == nil
+	}))
+	// rfc3339after=Other passes when the field is later than the sibling
+	// field Other; an unparsable sibling is left to its own rules.
+	must(v.RegisterValidation("rfc3339after", func(fl validator.FieldLevel) bool {
+		t, err := time.Parse(time.RFC3339, fl.Field().String())
+		if err != nil {
+			return false
+		}
+		other := fl.Parent().FieldByName(fl.Param())
+		if !other.IsValid() || other.Kind() != reflect.String {
+			return false
+		}
+		o, err := time.Parse(time.RFC3339, other.String())
+		return err != nil || t.After(o)
+	}))
+}
+
+// ValidPassport checks for a four digit series and a six digit number
+// separated by a space, e.g. "1234 567890".
+func ValidPassport(passport string) bool {
+	return passportPattern.MatchString(passport)
+}
+
+// Struct validates v against its binding tags.
+func Struct(v interface{}, lang Lang) error {
+	return Translate(binding.Validator.ValidateStruct(v), lang)
+}
+
+// Translate converts binding errors: rule violations become a
+// ValidationError listing every field, malformed bodies a BadRequestError.
+func Translate(err error, lang Lang) error {
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if errors.As(err, &fieldErrors) {
+		fields := make(map[string]string, len(fieldErrors))
+		for _, fe := range fieldErrors {
+			fields[fieldName(fe)] = fieldMessage(lang, fe)
+		}
+		return &apperrors.ValidationError{Message: Message(lang, "invalid"), Fields: fields}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &apperrors.ValidationError{
+			Message: Message(lang, "invalid"),
+			Fields:  map[string]string{typeErr.Field: Message(lang, "type")},
+		}
+	}
+	return &apperrors.BadRequestError{Message: Message(lang, "malformed")}
+}
+
+// fieldName strips the struct name from the namespace, keeping the path of
+// nested fields.
+func fieldName(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
@@ -8,5 +8,3 @@ CREATE TABLE users (
     created_at TIMESTAMPTZ NOT NULL,
     updated_at TIMESTAMPTZ NOT NULL
 );
-
-COMMIT;
\ No newline at end of file
@@ -8,6 +8,3 @@ CREATE TABLE tasks (
     updated_at TIMESTAMPTZ NOT NULL,
     FOREIGN KEY (user_id) REFERENCES users (id)
 );
-
-
-COMMIT;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS erasure_requests (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    requested_by VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    retained_time_records BOOLEAN NOT NULL,
+    tasks_affected INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tasks_deleted_at_idx;
+DROP INDEX IF EXISTS users_deleted_at_idx;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS users_full_name_trgm_idx;
+DROP INDEX IF EXISTS users_search_vector_idx;
+DROP INDEX IF EXISTS tasks_search_vector_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS full_name;
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,24 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('russian', coalesce(description, '')) ||
+        to_tsvector('english', coalesce(description, ''))
+    ) STORED;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(surname, '') || ' ' || coalesce(name, '') || ' ' || coalesce(patronymic, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(surname, '') || ' ' || coalesce(name, '') || ' ' || coalesce(patronymic, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(address, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(address, '')), 'B')
+    ) STORED;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS full_name TEXT
+    GENERATED ALWAYS AS (
+        coalesce(surname, '') || ' ' || coalesce(name, '') || ' ' || coalesce(patronymic, '')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS tasks_search_vector_idx ON tasks USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS users_search_vector_idx ON users USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS users_full_name_trgm_idx ON users USING GIN (full_name gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- Buckets are cheap to lose, so the table skips the WAL.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(512) PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS webhook_deliveries_event_idx;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS redelivery_of;
+DROP TABLE IF EXISTS outbox;
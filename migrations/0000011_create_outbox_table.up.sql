@@ -0,0 +1,31 @@
+-- Domain events written in the same transaction as the change they describe
+-- and published by the relay.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id INT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_published_at_idx ON outbox (published_at) WHERE published_at IS NOT NULL;
+
+-- The webhook sink may see an event more than once; only redeliveries may
+-- repeat an event for a subscription.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS redelivery_of BIGINT;
+UPDATE webhook_deliveries d
+SET redelivery_of = (
+    SELECT min(e.id) FROM webhook_deliveries e
+    WHERE e.subscription_id = d.subscription_id AND e.event_id = d.event_id
+)
+WHERE EXISTS (
+    SELECT 1 FROM webhook_deliveries e
+    WHERE e.subscription_id = d.subscription_id AND e.event_id = d.event_id AND e.id < d.id
+);
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_event_idx ON webhook_deliveries (subscription_id, event_id) WHERE redelivery_of IS NULL;
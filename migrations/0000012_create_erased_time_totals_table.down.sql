@@ -0,0 +1 @@
+DROP TABLE IF EXISTS erased_time_totals;
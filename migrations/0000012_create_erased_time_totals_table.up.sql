@@ -0,0 +1,7 @@
+-- Time records of erased users, kept for accounting as daily totals across
+-- all erased users. Nothing links a total back to a user.
+CREATE TABLE IF NOT EXISTS erased_time_totals (
+    day DATE PRIMARY KEY,
+    tasks INT NOT NULL,
+    duration_seconds BIGINT NOT NULL
+);
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL schema migrations so that the service
+// can apply them itself on startup.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS